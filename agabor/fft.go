@@ -0,0 +1,182 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agabor
+
+import (
+	"math"
+	"sync"
+
+	"github.com/chewxy/math32"
+	"github.com/emer/etable/etensor"
+)
+
+// nextPow2 returns the smallest power of 2 that is >= n
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft does an in-place radix-2 fast fourier transform of a, whose length must be a power of 2 --
+// inv does the inverse transform, including the 1/n normalization
+func fft(a []complex128, inv bool) {
+	n := len(a)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	sign := -1.0
+	if inv {
+		sign = 1.0
+	}
+	for ln := 2; ln <= n; ln <<= 1 {
+		sin, cos := math.Sincos(sign * 2 * math.Pi / float64(ln))
+		wl := complex(cos, sin)
+		half := ln / 2
+		for st := 0; st < n; st += ln {
+			w := complex(1, 0)
+			for k := 0; k < half; k++ {
+				u := a[st+k]
+				v := a[st+k+half] * w
+				a[st+k] = u + v
+				a[st+k+half] = u - v
+				w *= wl
+			}
+		}
+	}
+	if inv {
+		s := complex(1/float64(n), 0)
+		for i := range a {
+			a[i] *= s
+		}
+	}
+}
+
+// fft2 does an in-place 2d fast fourier transform of a, stored as rows x cols (both powers of 2),
+// using col as scratch space of length rows
+func fft2(a []complex128, rows, cols int, col []complex128, inv bool) {
+	for r := 0; r < rows; r++ {
+		fft(a[r*cols:(r+1)*cols], inv)
+	}
+	for c := 0; c < cols; c++ {
+		for r := 0; r < rows; r++ {
+			col[r] = a[r*cols+c]
+		}
+		fft(col, inv)
+		for r := 0; r < rows; r++ {
+			a[r*cols+c] = col[r]
+		}
+	}
+}
+
+// convFFT does the Conv filtering using fast fourier transforms: the input region covered by all
+// the filter positions is gathered once (with boundary handling) and transformed, and each filter
+// is then correlated with it in the frequency domain and the result sampled at the filter positions.
+// Shapes must have been validated by Conv
+func convFFT(ch int, params *Params, steps int, raw *etensor.Float32, melFilterCount int, gaborFilters *etensor.Float32, melData *etensor.Float32, nFreq, nTime int) {
+	sizeTime, sizeFreq := params.MaxSize()
+	nf := params.NFilters
+	avail := melData.Dim(0)
+	nch := melData.Dim(2)
+	fltSz := sizeFreq * sizeTime
+	flts := gaborFilters.Values
+	mels := melData.Values
+	outs := raw.Values
+
+	tBase := BoundStart(0, sizeTime, params.SpaceTime, params.BoundTime)
+	fBase := BoundStart(0, sizeFreq, params.SpaceFreq, params.BoundFreq)
+	tLen := BoundStart(nTime-1, sizeTime, params.SpaceTime, params.BoundTime) - tBase + sizeTime
+	fLen := BoundStart(nFreq-1, sizeFreq, params.SpaceFreq, params.BoundFreq) - fBase + sizeFreq
+	cols := nextPow2(tLen)
+	rows := nextPow2(fLen)
+
+	in := make([]complex128, rows*cols)
+	for ff := 0; ff < fLen; ff++ {
+		fi, fok := BoundIdx(fBase+ff, melFilterCount, melFilterCount, params.BoundFreq)
+		if !fok {
+			continue
+		}
+		for ft := 0; ft < tLen; ft++ {
+			ti, tok := BoundIdx(tBase+ft, steps, avail, params.BoundTime)
+			if tok {
+				in[ff*cols+ft] = complex(float64(mels[(ti*melFilterCount+fi)*nch+ch]), 0)
+			}
+		}
+	}
+	fft2(in, rows, cols, make([]complex128, rows), false)
+
+	// filters are processed in parallel, each goroutine doing a range of them
+	nFlt := params.NScales() * nf
+	convFlts := func(st, ed int) {
+		buf := make([]complex128, rows*cols)
+		col := make([]complex128, rows)
+		for fl := st; fl < ed; fl++ {
+			sc := fl / nf
+			fi := fl % nf
+			for i := range buf {
+				buf[i] = 0
+			}
+			fst := fl * fltSz
+			for ff := 0; ff < sizeFreq; ff++ {
+				for ft := 0; ft < sizeTime; ft++ {
+					buf[ff*cols+ft] = complex(float64(flts[fst+ff*sizeTime+ft]), 0)
+				}
+			}
+			fft2(buf, rows, cols, col, false)
+			// correlation: input spectrum times conjugate of filter spectrum
+			for i, h := range buf {
+				buf[i] = in[i] * complex(real(h), -imag(h))
+			}
+			fft2(buf, rows, cols, col, true)
+			for fIdx := 0; fIdx < nFreq; fIdx++ {
+				row := (BoundStart(fIdx, sizeFreq, params.SpaceFreq, params.BoundFreq) - fBase) * cols
+				for tIdx := 0; tIdx < nTime; tIdx++ {
+					p := row + BoundStart(tIdx, sizeTime, params.SpaceTime, params.BoundTime) - tBase
+					fSum := float32(real(buf[p]))
+					oi := raw.Offset([]int{ch, sc, fIdx, tIdx, 0, 0})
+					act := params.Gain * math32.Abs(fSum)
+					if fSum >= 0.0 {
+						outs[oi+fi] = act
+						outs[oi+nf+fi] = 0
+					} else {
+						outs[oi+fi] = 0
+						outs[oi+nf+fi] = act
+					}
+				}
+			}
+		}
+	}
+
+	nThreads := params.NThreads
+	if nThreads > nFlt {
+		nThreads = nFlt
+	}
+	if nThreads <= 1 {
+		convFlts(0, nFlt)
+		return
+	}
+	var wg sync.WaitGroup
+	per := (nFlt + nThreads - 1) / nThreads
+	for st := 0; st < nFlt; st += per {
+		ed := st + per
+		if ed > nFlt {
+			ed = nFlt
+		}
+		wg.Add(1)
+		go func(st, ed int) {
+			convFlts(st, ed)
+			wg.Done()
+		}(st, ed)
+	}
+	wg.Wait()
+}
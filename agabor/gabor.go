@@ -5,7 +5,9 @@
 package agabor
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/chewxy/math32"
 	"github.com/emer/etable/etensor"
@@ -13,32 +15,66 @@ import (
 
 // Gabor params for auditory gabor filters: 2d Gaussian envelope times a sinusoidal plane wave --
 // by default produces 2 phase asymmetric edge detector filters -- horizontal tuning is different from V1 version --
-// has elongated frequency-band specific tuning, not a parallel horizontal tuning -- and has multiple of these
+// has elongated frequency-band specific tuning, not a parallel horizontal tuning -- and has multiple of these.
+// The filter bank can have multiple scales (Sizes), each of which has the same set of horizontal and oriented filters
 type Params struct {
-	On              bool    `desc:"use this gabor filtering of the time-frequency space filtered input (time in terms of steps of the DFT transform, and discrete frequency factors based on the FFT window and input sample rate)"`
-	SizeTime        int     `viewif:"On" def:"6,8,12,16,24" desc:" size of the filter in the time (horizontal) domain, in terms of steps of the underlying DFT filtering steps"`
-	SizeFreq        int     `viewif:"On" def:"6,8,12,16,24" desc:" size of the filter in the frequency domain, in terms of discrete frequency factors based on the FFT window and input sample rate"`
-	SpaceTime       int     `viewif:"On" desc:" spacing in the time (horizontal) domain, in terms of steps"`
-	SpaceFreq       int     `viewif:"On" desc:" spacing in the frequency (vertical) domain"`
-	WaveLen         float32 `viewif:"On" def:"1.5,2" desc:"wavelength of the sine waves in normalized units"`
-	SigmaLen        float32 `viewif:"On" def:"0.6" desc:"gaussian sigma for the length dimension (elongated axis perpendicular to the sine waves) -- normalized as a function of filter size in relevant dimension"`
-	SigmaWidth      float32 `viewif:"On" def:"0.3" desc:"gaussian sigma for the width dimension (in the direction of the sine waves) -- normalized as a function of filter size in relevant dimension"`
-	HorizSigmaLen   float32 `viewif:"On" def:"0.3" desc:"gaussian sigma for the length of special horizontal narrow-band filters -- normalized as a function of filter size in relevant dimension"`
-	HorizSigmaWidth float32 `viewif:"On" def:"0.1" desc:"gaussian sigma for the horizontal dimension for special horizontal narrow-band filters -- normalized as a function of filter size in relevant dimension"`
-	Gain            float32 `viewif:"On" def:"2" desc:"overall gain multiplier applied after gabor filtering -- only relevant if not using renormalization (otherwize it just gets renormed awaY"`
-	NHoriz          int     `viewif:"On" def:"4" desc:"number of horizontally-elongated,  pure time-domain, frequency-band specific filters to include, evenly spaced over the available frequency space for this filter set -- in addition to these, there are two diagonals (45, 135) and a vertically-elongated (wide frequency band) filter"`
-	PhaseOffset     float32 `viewif:"On" def:"0,1.5708" desc:"offset for the sine phase -- default is an asymmetric sine wave -- can make it into a symmetric cosine gabor by using PI/2 = 1.5708"`
-	CircleEdge      bool    `viewif:"On" def:"true" desc:"cut off the filter (to zero) outside a circle of diameter filter_size -- makes the filter more radially symmetric"`
-	NFilters        int     `viewif:"On" desc:" total number of filters = 3 + NHoriz"`
+	On              bool       `desc:"use this gabor filtering of the time-frequency space filtered input (time in terms of steps of the DFT transform, and discrete frequency factors based on the FFT window and input sample rate)"`
+	Sizes           []Size     `viewif:"On" desc:"sizes of the filters, one per scale of the filter bank -- filters of all sizes are centered on the same time / frequency positions and their outputs are stacked along the scale dimension"`
+	SpaceTime       int        `viewif:"On" desc:" spacing in the time (horizontal) domain, in terms of steps"`
+	SpaceFreq       int        `viewif:"On" desc:" spacing in the frequency (vertical) domain"`
+	WaveLen         float32    `viewif:"On" def:"1.5,2" desc:"wavelength of the sine waves in normalized units"`
+	SigmaLen        float32    `viewif:"On" def:"0.6" desc:"gaussian sigma for the length dimension (elongated axis perpendicular to the sine waves) -- normalized as a function of filter size in relevant dimension"`
+	SigmaWidth      float32    `viewif:"On" def:"0.3" desc:"gaussian sigma for the width dimension (in the direction of the sine waves) -- normalized as a function of filter size in relevant dimension"`
+	HorizSigmaLen   float32    `viewif:"On" def:"0.3" desc:"gaussian sigma for the length of special horizontal narrow-band filters -- normalized as a function of filter size in relevant dimension"`
+	HorizSigmaWidth float32    `viewif:"On" def:"0.1" desc:"gaussian sigma for the horizontal dimension for special horizontal narrow-band filters -- normalized as a function of filter size in relevant dimension"`
+	Gain            float32    `viewif:"On" def:"2" desc:"overall gain multiplier applied after gabor filtering -- only relevant if not using renormalization (otherwize it just gets renormed awaY"`
+	NHoriz          int        `viewif:"On" def:"4" desc:"number of horizontally-elongated,  pure time-domain, frequency-band specific filters to include, evenly spaced over the available frequency space for this filter set -- in addition to these, there is one oriented filter for each of the Angles"`
+	Angles          []float32  `viewif:"On" def:"45,90,135" desc:"angles in degrees of the oriented (full filter size) filters -- 90 is a vertically-elongated (wide frequency band) filter, 45 and 135 are the two diagonals"`
+	PhaseOffset     float32    `viewif:"On" def:"0,1.5708" desc:"offset for the sine phase -- default is an asymmetric sine wave -- can make it into a symmetric cosine gabor by using PI/2 = 1.5708"`
+	CircleEdge      bool       `viewif:"On" def:"true" desc:"cut off the filter (to zero) outside a circle of diameter filter_size -- makes the filter more radially symmetric"`
+	BoundTime       Boundary   `viewif:"On" def:"Overlap" desc:"how filters are applied at the start and end of the segment in the time domain"`
+	BoundFreq       Boundary   `viewif:"On" def:"Valid" desc:"how filters are applied at the low and high ends of the frequency domain -- Overlap is not allowed here"`
+	NThreads        int        `viewif:"On" def:"0" desc:"number of goroutines Conv uses, each processing a range of time positions (or of filters, for FFT) -- 0 or 1 = no parallelism"`
+	FFT             bool       `viewif:"On" desc:"Conv uses fast fourier transforms of the whole segment instead of direct sums at each filter position -- same results within floating point tolerance -- which is faster depends on the filter sizes and spacing, see BenchmarkConv"`
+	Pool            PoolParams `viewif:"On" view:"inline" desc:"optional pooling of the Conv outputs over frequency / time neighborhoods -- applied by PoolKWTA"`
+	KWTA            KWTAParams `viewif:"On" view:"inline" desc:"optional k-winners-take-all competition among the (pooled) outputs -- applied by PoolKWTA"`
+	NFilters        int        `viewif:"On" inactive:"+" desc:" number of filters per scale = NHoriz + number of Angles -- set by RenderFilters"`
 }
 
-//Initialize initializes the Gabor
-func (ga *Params) Defaults(steps int, melFilters int) {
+// Boundary determines how Conv handles filter positions that extend beyond the edges of the input
+type Boundary int32
+
+const (
+	// Valid only computes outputs at positions where the filter lies entirely within the input
+	Valid = iota
+
+	// Zero centers the filters on each position and treats input outside the edges as zero
+	Zero
+
+	// Reflect centers the filters on each position and mirrors the input at the edges
+	Reflect
+
+	// Overlap starts the filters at each position, and at the end of the segment reads the
+	// steps that overlap the next segment (SegmentStepsPlus) -- input beyond those is zero
+	Overlap
+
+	BoundaryN
+)
+
+//go:generate stringer -type=Boundary
+
+// Size is the time and frequency extent of the filters at one scale of the filter bank
+type Size struct {
+	Time int `def:"6,8,12,16,24" desc:" size of the filter in the time (horizontal) domain, in terms of steps of the underlying DFT filtering steps"`
+	Freq int `def:"6,8,12,16,24" desc:" size of the filter in the frequency domain, in terms of discrete frequency factors based on the FFT window and input sample rate"`
+}
+
+// Defaults initializes the Gabor params to a single scale bank of 6 x 6 filters
+func (ga *Params) Defaults() {
 	ga.On = true
 	ga.Gain = 2.0
 	ga.NHoriz = 4
-	ga.SizeTime = 6.0
-	ga.SizeFreq = 6.0
+	ga.Sizes = []Size{{Time: 6, Freq: 6}}
 	ga.SpaceTime = 2.0
 	ga.SpaceFreq = 2.0
 	ga.WaveLen = 2.0
@@ -48,16 +84,127 @@ func (ga *Params) Defaults(steps int, melFilters int) {
 	ga.HorizSigmaWidth = 0.1
 	ga.PhaseOffset = 0.0
 	ga.CircleEdge = true
-	ga.NFilters = 3 + ga.NHoriz // 3 is number of angle filters
+	ga.BoundTime = Overlap
+	ga.BoundFreq = Valid
+	ga.Pool.Defaults()
+	ga.KWTA.Defaults()
+	ga.Angles = []float32{45, 90, 135}
+	ga.NFilters = ga.NHoriz + len(ga.Angles)
+}
+
+// NScales returns the number of scales (filter sizes) in the filter bank
+func (ga *Params) NScales() int {
+	return len(ga.Sizes)
+}
+
+// MaxSize returns the largest time and frequency size over all scales -- the filters
+// tensor is allocated at this size with smaller filters centered within it
+func (ga *Params) MaxSize() (sizeTime, sizeFreq int) {
+	for _, sz := range ga.Sizes {
+		if sz.Time > sizeTime {
+			sizeTime = sz.Time
+		}
+		if sz.Freq > sizeFreq {
+			sizeFreq = sz.Freq
+		}
+	}
+	return
+}
+
+// OutSize returns the number of frequency and time positions that Conv produces for
+// the given number of mel filters and segment steps, according to the boundary modes
+func (ga *Params) OutSize(steps int, melFilters int) (nFreq, nTime int) {
+	sizeTime, sizeFreq := ga.MaxSize()
+	nTime = BoundSize(steps, sizeTime, ga.SpaceTime, ga.BoundTime)
+	nFreq = BoundSize(melFilters, sizeFreq, ga.SpaceFreq, ga.BoundFreq)
+	return
+}
+
+// BoundSize returns the number of filter positions over n input values for a filter
+// of the given size and spacing, for given boundary mode
+func BoundSize(n, size, space int, bound Boundary) int {
+	if space <= 0 {
+		return 0
+	}
+	if bound == Valid {
+		if n < size {
+			return 0
+		}
+		return ((n - size) / space) + 1
+	}
+	if n <= 0 {
+		return 0
+	}
+	return ((n - 1) / space) + 1
+}
+
+// BoundStart returns the input index of the first filter element for filter position idx,
+// for a filter of the given size and spacing, for given boundary mode
+func BoundStart(idx, size, space int, bound Boundary) int {
+	switch bound {
+	case Zero, Reflect:
+		return idx*space - size/2
+	}
+	return idx * space
+}
+
+// BoundIdx maps input index i onto an input of n values (avail of which are actually
+// present in the data, avail >= n) according to the boundary mode, returning false if the input
+// at that index is to be treated as zero
+func BoundIdx(i, n, avail int, bound Boundary) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch bound {
+	case Reflect:
+		if n == 1 {
+			return 0, true
+		}
+		for i < 0 || i >= n {
+			if i < 0 {
+				i = -i
+			}
+			if i >= n {
+				i = 2*(n-1) - i
+			}
+		}
+		return i, true
+	case Overlap:
+		if i >= n && i < avail {
+			return i, true
+		}
+	}
+	return 0, false
 }
 
-// RenderFilters generates filters into the given matrix, which is formatted as: [ga.SizeTime_steps][ga.SizeFreq][n_filters]
+// OutShape returns the shape of the Conv output tensor for the given number of channels,
+// mel filters and segment steps: [channels][scales][freq][time][on/off][filters]
+func (ga *Params) OutShape(channels, steps, melFilters int) []int {
+	nFreq, nTime := ga.OutSize(steps, melFilters)
+	return []int{channels, ga.NScales(), nFreq, nTime, 2, ga.NFilters}
+}
+
+// RenderFilters generates the filters for all scales into the given tensor, which is shaped
+// as: [scales][filters][max SizeFreq][max SizeTime] -- filters smaller than the max size are
+// centered within the max size and zero elsewhere
 func (ga *Params) RenderFilters(filters *etensor.Float32) {
-	ctrTime := (float32(ga.SizeTime) - 1) / 2.0
-	ctrFreq := (float32(ga.SizeFreq) - 1) / 2.0
+	ga.NFilters = ga.NHoriz + len(ga.Angles)
+	maxTime, maxFreq := ga.MaxSize()
+	filters.SetShape([]int{ga.NScales(), ga.NFilters, maxFreq, maxTime}, nil, []string{"Scale", "Filter", "Freq", "Time"})
+	filters.SetZeros()
+	for sc, sz := range ga.Sizes {
+		ga.RenderScale(filters, sc, sz, (maxTime-sz.Time)/2, (maxFreq-sz.Freq)/2)
+	}
+}
+
+// RenderScale generates the filters for one scale of the bank, of the given size,
+// at the given time and frequency offsets within the filters tensor
+func (ga *Params) RenderScale(filters *etensor.Float32, sc int, sz Size, offTime, offFreq int) {
+	ctrTime := (float32(sz.Time) - 1) / 2.0
+	ctrFreq := (float32(sz.Freq) - 1) / 2.0
 	angInc := math32.Pi / 4.0
-	radiusTime := float32(ga.SizeTime / 2.0)
-	radiusFreq := float32(ga.SizeFreq / 2.0)
+	radiusTime := float32(sz.Time / 2.0)
+	radiusFreq := float32(sz.Freq / 2.0)
 
 	lenNorm := 1.0 / (2.0 * ga.SigmaLen * ga.SigmaLen)
 	widthNorm := 1.0 / (2.0 * ga.SigmaWidth * ga.SigmaWidth)
@@ -65,15 +212,15 @@ func (ga *Params) RenderFilters(filters *etensor.Float32) {
 	widthHorizNorm := 1.0 / (2.0 * ga.HorizSigmaWidth * ga.HorizSigmaWidth)
 
 	twoPiNorm := (2.0 * math32.Pi) / ga.WaveLen
-	hCtrInc := (ga.SizeFreq - 1) / (ga.NHoriz + 1)
+	hCtrInc := (sz.Freq - 1) / (ga.NHoriz + 1)
 
 	fli := 0
 	for hi := 0; hi < ga.NHoriz; hi, fli = hi+1, fli+1 {
 		hCtrFreq := hCtrInc * (hi + 1)
 		angF := -2.0 * angInc
-		for y := 0; y < ga.SizeFreq; y++ {
+		for y := 0; y < sz.Freq; y++ {
 			var xf, yf, xfn, yfn float32
-			for x := 0; x < ga.SizeTime; x++ {
+			for x := 0; x < sz.Time; x++ {
 				xf = float32(x) - ctrTime
 				yf = float32(y) - float32(hCtrFreq)
 				xfn = xf / radiusTime
@@ -88,17 +235,17 @@ func (ga *Params) RenderFilters(filters *etensor.Float32) {
 					sinVal := math32.Sin(twoPiNorm*ny + ga.PhaseOffset)
 					val = gauss * sinVal
 				}
-				filters.Set([]int{fli, y, x}, val)
+				filters.Set([]int{sc, fli, offFreq + y, offTime + x}, val)
 			}
 		}
 	}
 
-	// fli should be ga.Horiz - 1 at this point
-	for ang := 1; ang < 4; ang, fli = ang+1, fli+1 {
-		angF := float32(-ang) * angInc
+	// fli should be ga.NHoriz at this point
+	for _, ang := range ga.Angles {
+		angF := -ang * math32.Pi / 180.0
 		var xf, yf, xfn, yfn float32
-		for y := 0; y < ga.SizeFreq; y++ {
-			for x := 0; x < ga.SizeTime; x++ {
+		for y := 0; y < sz.Freq; y++ {
+			for x := 0; x < sz.Time; x++ {
 				xf = float32(x) - ctrTime
 				yf = float32(y) - ctrFreq
 				xfn = xf / radiusTime
@@ -113,18 +260,19 @@ func (ga *Params) RenderFilters(filters *etensor.Float32) {
 					sinVal := math32.Sin(twoPiNorm*ny + ga.PhaseOffset)
 					val = gauss * sinVal
 				}
-				filters.Set([]int{fli, y, x}, val)
+				filters.Set([]int{sc, fli, offFreq + y, offTime + x}, val)
 			}
 		}
+		fli++
 	}
 
 	// renorm each half
 	for fli := 0; fli < ga.NFilters; fli++ {
 		posSum := float32(0)
 		negSum := float32(0)
-		for y := 0; y < ga.SizeFreq; y++ {
-			for x := 0; x < ga.SizeTime; x++ {
-				val := float32(filters.Value([]int{fli, y, x}))
+		for y := offFreq; y < offFreq+sz.Freq; y++ {
+			for x := offTime; x < offTime+sz.Time; x++ {
+				val := float32(filters.Value([]int{sc, fli, y, x}))
 				if val > 0 {
 					posSum += val
 				} else if val < 0 {
@@ -134,67 +282,174 @@ func (ga *Params) RenderFilters(filters *etensor.Float32) {
 		}
 		posNorm := 1.0 / posSum
 		negNorm := -1.0 / negSum
-		for y := 0; y < ga.SizeFreq; y++ {
-			for x := 0; x < ga.SizeTime; x++ {
-				val := filters.Value([]int{fli, y, x})
+		for y := offFreq; y < offFreq+sz.Freq; y++ {
+			for x := offTime; x < offTime+sz.Time; x++ {
+				val := filters.Value([]int{sc, fli, y, x})
 				if val > 0.0 {
 					val *= posNorm
 				} else if val < 0.0 {
 					val *= negNorm
 				}
-				filters.Set([]int{fli, y, x}, val)
+				filters.Set([]int{sc, fli, y, x}, val)
 			}
 		}
 	}
 }
 
-// Conv processes input using filters that operate over an entire segment of samples
-func Conv(ch int, params Params, steps int, raw *etensor.Float32, melFilterCount int, gaborFilters *etensor.Float32, melData *etensor.Float32) {
-	tHalfSz := params.SizeTime / 2
-	tOff := tHalfSz
-	tMin := tOff
-	if tMin < 0 {
-		tMin = 0
-	}
-	tMax := steps - tMin + 1
-
-	fMin := int(0)
-	fMax := melFilterCount - params.SizeFreq
-
-	maxTimeIdx := raw.Dim(2) // dim 0 is channel
-	tIdx := 0
-	for s := tMin; s < tMax; s, tIdx = s+params.SpaceTime, tIdx+1 {
-		inSt := s - tOff
-		if tIdx > maxTimeIdx {
-			log.Printf("gabor.GaborFilter: time index %v out of range: %v", tIdx, maxTimeIdx)
-			break
+// Validate checks that the params are usable for filtering n mel filters over segments of the given number of steps
+func (ga *Params) Validate(steps int, melFilters int) error {
+	if ga.NScales() == 0 {
+		return errors.New("agabor.Validate: no filter Sizes specified")
+	}
+	for _, sz := range ga.Sizes {
+		if sz.Time <= 0 || sz.Freq <= 0 {
+			return fmt.Errorf("agabor.Validate: filter size must be positive: %v", sz)
 		}
+	}
+	if ga.SpaceTime <= 0 || ga.SpaceFreq <= 0 {
+		return fmt.Errorf("agabor.Validate: spacing must be positive, SpaceTime: %v SpaceFreq: %v", ga.SpaceTime, ga.SpaceFreq)
+	}
+	if ga.BoundTime < Valid || ga.BoundTime >= BoundaryN {
+		return fmt.Errorf("agabor.Validate: invalid BoundTime: %v", ga.BoundTime)
+	}
+	if ga.BoundFreq < Valid || ga.BoundFreq >= BoundaryN || ga.BoundFreq == Overlap {
+		return fmt.Errorf("agabor.Validate: invalid BoundFreq: %v", ga.BoundFreq)
+	}
+	nFreq, nTime := ga.OutSize(steps, melFilters)
+	if nFreq <= 0 || nTime <= 0 {
+		maxTime, maxFreq := ga.MaxSize()
+		return fmt.Errorf("agabor.Validate: filter size %v x %v (time x freq) too large for %v steps x %v mel filters", maxTime, maxFreq, steps, melFilters)
+	}
+	return nil
+}
 
-		maxFreqIdx := raw.Dim(1) // dim 0 is channel
-		fIdx := 0
-		for flt := fMin; flt < fMax; flt, fIdx = flt+params.SpaceFreq, fIdx+1 {
-			if fIdx > maxFreqIdx {
-				log.Printf("gabor.GaborFilter: freq index %v out of range: %v", tIdx, maxFreqIdx)
-				break
+// Conv processes input using filters that operate over an entire segment of samples.
+// steps is the number of steps in the segment proper -- melData, shaped [steps][melFilterCount][channels],
+// can have more steps than this (SegmentStepsPlus), which are used by the Overlap time boundary mode.
+// The gaborFilters are as produced by RenderFilters and raw must be shaped according to OutShape.
+// params.FFT selects fast fourier transform filtering instead of the direct sums.
+// Returns an error, without filtering, if any of the shapes are inconsistent
+func Conv(ch int, params Params, steps int, raw *etensor.Float32, melFilterCount int, gaborFilters *etensor.Float32, melData *etensor.Float32) error {
+	err := params.Validate(steps, melFilterCount)
+	if err != nil {
+		return err
+	}
+	sizeTime, sizeFreq := params.MaxSize()
+	nScales := params.NScales()
+	nf := params.NFilters
+	if gaborFilters.NumDims() != 4 || gaborFilters.Dim(0) != nScales || gaborFilters.Dim(1) != nf || gaborFilters.Dim(2) != sizeFreq || gaborFilters.Dim(3) != sizeTime {
+		return fmt.Errorf("agabor.Conv: filters shape %v does not match params: %v", gaborFilters.Shapes(), []int{nScales, nf, sizeFreq, sizeTime})
+	}
+	if melData.NumDims() != 3 || melData.Dim(0) < steps || melData.Dim(1) != melFilterCount || ch < 0 || ch >= melData.Dim(2) {
+		return fmt.Errorf("agabor.Conv: mel data shape %v does not contain %v steps x %v mel filters for channel %v", melData.Shapes(), steps, melFilterCount, ch)
+	}
+	oshp := params.OutShape(melData.Dim(2), steps, melFilterCount)
+	if raw.NumDims() != len(oshp) || ch >= raw.Dim(0) {
+		return fmt.Errorf("agabor.Conv: output shape %v does not match expected shape %v", raw.Shapes(), oshp)
+	}
+	for d := 1; d < len(oshp); d++ {
+		if raw.Dim(d) != oshp[d] {
+			return fmt.Errorf("agabor.Conv: output shape %v does not match expected shape %v", raw.Shapes(), oshp)
+		}
+	}
+	nFreq := oshp[2]
+	nTime := oshp[3]
+
+	if params.FFT {
+		convFFT(ch, &params, steps, raw, melFilterCount, gaborFilters, melData, nFreq, nTime)
+		return nil
+	}
+	nThreads := params.NThreads
+	if nThreads > nTime {
+		nThreads = nTime
+	}
+	if nThreads <= 1 {
+		convTimes(ch, &params, steps, raw, melFilterCount, gaborFilters, melData, 0, nTime, nFreq)
+		return nil
+	}
+	var wg sync.WaitGroup
+	per := (nTime + nThreads - 1) / nThreads
+	for st := 0; st < nTime; st += per {
+		ed := st + per
+		if ed > nTime {
+			ed = nTime
+		}
+		wg.Add(1)
+		go func(st, ed int) {
+			convTimes(ch, &params, steps, raw, melFilterCount, gaborFilters, melData, st, ed, nFreq)
+			wg.Done()
+		}(st, ed)
+	}
+	wg.Wait()
+	return nil
+}
+
+// convTimes does the Conv filtering for output time positions from tSt up to tEd,
+// using direct access to the tensor values -- shapes must have been validated by Conv.
+// For each position, the input patch under the max-size filter is gathered once (with
+// boundary handling) and then each filter is applied only over its own size within that patch
+func convTimes(ch int, params *Params, steps int, raw *etensor.Float32, melFilterCount int, gaborFilters *etensor.Float32, melData *etensor.Float32, tSt, tEd, nFreq int) {
+	sizeTime, sizeFreq := params.MaxSize()
+	nf := params.NFilters
+	avail := melData.Dim(0)
+	nch := melData.Dim(2)
+	fltSz := sizeFreq * sizeTime
+	flts := gaborFilters.Values
+	mels := melData.Values
+	outs := raw.Values
+
+	// input indexes for each filter element at the current position, -1 = zero
+	tIn := make([]int, sizeTime)
+	fIn := make([]int, sizeFreq)
+	patch := make([]float32, fltSz)
+	for tIdx := tSt; tIdx < tEd; tIdx++ {
+		inSt := BoundStart(tIdx, sizeTime, params.SpaceTime, params.BoundTime)
+		for ft := 0; ft < sizeTime; ft++ {
+			ti, ok := BoundIdx(inSt+ft, steps, avail, params.BoundTime)
+			if !ok {
+				ti = -1
+			}
+			tIn[ft] = ti
+		}
+		for fIdx := 0; fIdx < nFreq; fIdx++ {
+			flt := BoundStart(fIdx, sizeFreq, params.SpaceFreq, params.BoundFreq)
+			for ff := 0; ff < sizeFreq; ff++ {
+				fi, ok := BoundIdx(flt+ff, melFilterCount, melFilterCount, params.BoundFreq)
+				if !ok {
+					fi = -1
+				}
+				fIn[ff] = fi
 			}
-			nf := params.NFilters
-			for fi := int(0); fi < nf; fi++ {
-				fSum := float32(0.0)
-				for ff := int(0); ff < params.SizeFreq; ff++ {
-					for ft := int(0); ft < params.SizeTime; ft++ {
-						fVal := gaborFilters.Value([]int{fi, ff, ft})
-						iVal := melData.Value([]int{inSt + ft, flt + ff, ch})
-						fSum += fVal * iVal
+			for ff := 0; ff < sizeFreq; ff++ {
+				for ft := 0; ft < sizeTime; ft++ {
+					val := float32(0)
+					if fIn[ff] >= 0 && tIn[ft] >= 0 {
+						val = mels[(tIn[ft]*melFilterCount+fIn[ff])*nch+ch]
 					}
+					patch[ff*sizeTime+ft] = val
 				}
-				pos := fSum >= 0.0
-				act := params.Gain * math32.Abs(fSum)
-				if pos {
-					raw.SetFloat([]int{ch, fIdx, tIdx, 0, fi}, float64(act))
-					raw.SetFloat([]int{ch, fIdx, tIdx, 1, fi}, 0)
-				} else {
-					raw.SetFloat([]int{ch, fIdx, tIdx, 0, fi}, 0)
-					raw.SetFloat([]int{ch, fIdx, tIdx, 1, fi}, float64(act))
+			}
+			for sc, sz := range params.Sizes {
+				offTime := (sizeTime - sz.Time) / 2
+				offFreq := (sizeFreq - sz.Freq) / 2
+				oi := raw.Offset([]int{ch, sc, fIdx, tIdx, 0, 0})
+				for fi := 0; fi < nf; fi++ {
+					fst := (sc*nf + fi) * fltSz
+					fSum := float32(0.0)
+					for ff := offFreq; ff < offFreq+sz.Freq; ff++ {
+						row := ff * sizeTime
+						for ft := offTime; ft < offTime+sz.Time; ft++ {
+							fSum += flts[fst+row+ft] * patch[row+ft]
+						}
+					}
+					act := params.Gain * math32.Abs(fSum)
+					if fSum >= 0.0 {
+						outs[oi+fi] = act
+						outs[oi+nf+fi] = 0
+					} else {
+						outs[oi+fi] = 0
+						outs[oi+nf+fi] = act
+					}
 				}
 			}
 		}
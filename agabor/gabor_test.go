@@ -0,0 +1,345 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agabor
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/emer/etable/etensor"
+)
+
+func TestRenderFilters(t *testing.T) {
+	var ga Params
+	ga.Defaults()
+	ga.Sizes = []Size{{Time: 6, Freq: 6}, {Time: 12, Freq: 8}}
+	ga.NHoriz = 2
+	ga.Angles = []float32{0, 45, 90, 135}
+	var flts etensor.Float32
+	ga.RenderFilters(&flts)
+
+	if ga.NFilters != 6 {
+		t.Fatalf("NFilters = %d, want 6", ga.NFilters)
+	}
+	want := []int{2, 6, 8, 12}
+	for d, n := range want {
+		if flts.Dim(d) != n {
+			t.Fatalf("filters shape = %v, want %v", flts.Shapes(), want)
+		}
+	}
+	for sc, sz := range ga.Sizes {
+		offTime := (12 - sz.Time) / 2
+		offFreq := (8 - sz.Freq) / 2
+		for fi := 0; fi < ga.NFilters; fi++ {
+			pos, neg := 0.0, 0.0
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 12; x++ {
+					v := flts.Value([]int{sc, fi, y, x})
+					in := y >= offFreq && y < offFreq+sz.Freq && x >= offTime && x < offTime+sz.Time
+					if !in && v != 0 {
+						t.Errorf("scale %d filter %d: nonzero value %v outside of its size at %d, %d", sc, fi, v, y, x)
+					}
+					if v > 0 {
+						pos += float64(v)
+					} else {
+						neg += float64(v)
+					}
+				}
+			}
+			if math.Abs(pos-1) > 1e-4 || math.Abs(neg+1) > 1e-4 {
+				t.Errorf("scale %d filter %d: positive sum %v and negative sum %v, want 1 and -1", sc, fi, pos, neg)
+			}
+		}
+	}
+}
+
+func TestBoundSize(t *testing.T) {
+	tests := []struct {
+		n, size, space int
+		bound          Boundary
+		want           int
+	}{
+		{10, 4, 2, Valid, 4},
+		{10, 4, 2, Zero, 5},
+		{10, 4, 2, Reflect, 5},
+		{10, 4, 2, Overlap, 5},
+		{3, 4, 2, Valid, 0},
+		{3, 4, 2, Zero, 2},
+		{10, 4, 0, Zero, 0},
+	}
+	for _, tt := range tests {
+		if got := BoundSize(tt.n, tt.size, tt.space, tt.bound); got != tt.want {
+			t.Errorf("BoundSize(%d, %d, %d, %d) = %d, want %d", tt.n, tt.size, tt.space, tt.bound, got, tt.want)
+		}
+	}
+	if got := BoundStart(3, 6, 2, Zero); got != 3 {
+		t.Errorf("BoundStart Zero = %d, want 3", got)
+	}
+	if got := BoundStart(3, 6, 2, Valid); got != 6 {
+		t.Errorf("BoundStart Valid = %d, want 6", got)
+	}
+}
+
+func TestBoundIdx(t *testing.T) {
+	tests := []struct {
+		i, n, avail int
+		bound       Boundary
+		want        int
+		ok          bool
+	}{
+		{2, 5, 5, Valid, 2, true},
+		{-1, 5, 5, Valid, 0, false},
+		{-1, 5, 5, Zero, 0, false},
+		{5, 5, 5, Zero, 0, false},
+		{-1, 5, 5, Reflect, 1, true},
+		{-2, 5, 5, Reflect, 2, true},
+		{5, 5, 5, Reflect, 3, true},
+		{6, 5, 5, Reflect, 2, true},
+		{-3, 1, 1, Reflect, 0, true},
+		{5, 5, 7, Overlap, 5, true},
+		{7, 5, 7, Overlap, 0, false},
+		{-1, 5, 7, Overlap, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := BoundIdx(tt.i, tt.n, tt.avail, tt.bound)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("BoundIdx(%d, %d, %d, %d) = %d, %v, want %d, %v", tt.i, tt.n, tt.avail, tt.bound, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(ga *Params)
+		ok   bool
+	}{
+		{"defaults", func(ga *Params) {}, true},
+		{"no sizes", func(ga *Params) { ga.Sizes = nil }, false},
+		{"zero size", func(ga *Params) { ga.Sizes = []Size{{Time: 6, Freq: 0}} }, false},
+		{"zero spacing", func(ga *Params) { ga.SpaceTime = 0 }, false},
+		{"invalid time bound", func(ga *Params) { ga.BoundTime = BoundaryN }, false},
+		{"overlap freq bound", func(ga *Params) { ga.BoundFreq = Overlap }, false},
+		{"too large", func(ga *Params) { ga.Sizes = []Size{{Time: 6, Freq: 20}} }, false},
+		{"too large zero bound", func(ga *Params) { ga.Sizes = []Size{{Time: 6, Freq: 20}}; ga.BoundFreq = Zero }, true},
+	}
+	for _, tt := range tests {
+		var ga Params
+		ga.Defaults()
+		tt.set(&ga)
+		err := ga.Validate(20, 16)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Validate error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestConvShapeErrors(t *testing.T) {
+	var ga Params
+	ga.Defaults()
+	var flts, mels, raw etensor.Float32
+	ga.RenderFilters(&flts)
+	mels.SetShape([]int{20, 16, 1}, nil, nil)
+	raw.SetShape(ga.OutShape(1, 20, 16), nil, nil)
+	if err := Conv(0, ga, 20, &raw, 16, &flts, &mels); err != nil {
+		t.Fatalf("Conv error: %v", err)
+	}
+	if err := Conv(1, ga, 20, &raw, 16, &flts, &mels); err == nil {
+		t.Errorf("Conv with channel out of range: no error")
+	}
+	if err := Conv(0, ga, 20, &raw, 12, &flts, &mels); err == nil {
+		t.Errorf("Conv with wrong mel filter count: no error")
+	}
+	var bad etensor.Float32
+	bad.SetShape([]int{1, 1, 2, 2, 2, ga.NFilters}, nil, nil)
+	if err := Conv(0, ga, 20, &bad, 16, &flts, &mels); err == nil {
+		t.Errorf("Conv with wrong output shape: no error")
+	}
+	ga.Sizes = []Size{{Time: 8, Freq: 8}}
+	if err := Conv(0, ga, 20, &raw, 16, &flts, &mels); err == nil {
+		t.Errorf("Conv with filters not matching the params: no error")
+	}
+}
+
+// refIdx maps input index i onto n values (avail present) for the boundary mode, as a reference for BoundIdx
+func refIdx(i, n, avail int, bound Boundary) (int, bool) {
+	switch {
+	case i >= 0 && i < n:
+		return i, true
+	case bound == Reflect:
+		if n == 1 {
+			return 0, true
+		}
+		p := 2 * (n - 1)
+		if i < 0 {
+			i = -i
+		}
+		i %= p
+		if i >= n {
+			i = p - i
+		}
+		return i, true
+	case bound == Overlap && i >= n && i < avail:
+		return i, true
+	}
+	return 0, false
+}
+
+// refConv is a straightforward reference implementation of Conv, applying each filter over the
+// full max size, which is zero outside of its own size
+func refConv(ch int, ga *Params, steps int, raw, flts, mels *etensor.Float32) {
+	sizeTime, sizeFreq := ga.MaxSize()
+	nMel := mels.Dim(1)
+	for fIdx := 0; fIdx < raw.Dim(2); fIdx++ {
+		fst := fIdx * ga.SpaceFreq
+		if ga.BoundFreq == Zero || ga.BoundFreq == Reflect {
+			fst -= sizeFreq / 2
+		}
+		for tIdx := 0; tIdx < raw.Dim(3); tIdx++ {
+			tst := tIdx * ga.SpaceTime
+			if ga.BoundTime == Zero || ga.BoundTime == Reflect {
+				tst -= sizeTime / 2
+			}
+			for sc := range ga.Sizes {
+				for fi := 0; fi < ga.NFilters; fi++ {
+					sum := 0.0
+					for ff := 0; ff < sizeFreq; ff++ {
+						f, fok := refIdx(fst+ff, nMel, nMel, ga.BoundFreq)
+						for ft := 0; ft < sizeTime; ft++ {
+							t, tok := refIdx(tst+ft, steps, mels.Dim(0), ga.BoundTime)
+							if fok && tok {
+								sum += float64(flts.Value([]int{sc, fi, ff, ft})) * float64(mels.Value([]int{t, f, ch}))
+							}
+						}
+					}
+					on, off := 0.0, 0.0
+					if sum >= 0 {
+						on = float64(ga.Gain) * sum
+					} else {
+						off = -float64(ga.Gain) * sum
+					}
+					raw.Set([]int{ch, sc, fIdx, tIdx, 0, fi}, float32(on))
+					raw.Set([]int{ch, sc, fIdx, tIdx, 1, fi}, float32(off))
+				}
+			}
+		}
+	}
+}
+
+// testMels returns mel data of the given shape, with deterministic pseudo-random values
+func testMels(steps, nMel, nCh int) *etensor.Float32 {
+	mels := etensor.NewFloat32([]int{steps, nMel, nCh}, nil, nil)
+	for i := range mels.Values {
+		mels.Values[i] = float32(math.Sin(float64(i)*0.37) + 0.5*math.Cos(float64(i)*1.13))
+	}
+	return mels
+}
+
+func TestConvReference(t *testing.T) {
+	const steps, avail, nMel = 20, 24, 16
+	mels := testMels(avail, nMel, 2)
+	for bt := Boundary(Valid); bt < BoundaryN; bt++ {
+		for bf := Boundary(Valid); bf < BoundaryN; bf++ {
+			if bf == Overlap {
+				continue
+			}
+			for _, nThr := range []int{1, 4} {
+				var ga Params
+				ga.Defaults()
+				ga.Sizes = []Size{{Time: 6, Freq: 6}, {Time: 8, Freq: 4}}
+				ga.BoundTime = bt
+				ga.BoundFreq = bf
+				ga.NThreads = nThr
+				var flts, raw, ref etensor.Float32
+				ga.RenderFilters(&flts)
+				oshp := ga.OutShape(2, steps, nMel)
+				raw.SetShape(oshp, nil, nil)
+				ref.SetShape(oshp, nil, nil)
+				if err := Conv(1, ga, steps, &raw, nMel, &flts, mels); err != nil {
+					t.Fatalf("time %v freq %v: Conv error: %v", bt, bf, err)
+				}
+				refConv(1, &ga, steps, &ref, &flts, mels)
+				for i, v := range raw.Values {
+					if math.Abs(float64(v-ref.Values[i])) > 1e-5 {
+						t.Errorf("time %v freq %v threads %d: value %d = %v, reference %v", bt, bf, nThr, i, v, ref.Values[i])
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestConvFFT(t *testing.T) {
+	const steps, avail, nMel = 37, 45, 23
+	mels := testMels(avail, nMel, 2)
+	for bt := Boundary(Valid); bt < BoundaryN; bt++ {
+		for bf := Boundary(Valid); bf < BoundaryN; bf++ {
+			if bf == Overlap {
+				continue
+			}
+			for _, nThr := range []int{1, 3} {
+				var ga Params
+				ga.Defaults()
+				ga.Sizes = []Size{{Time: 6, Freq: 6}, {Time: 12, Freq: 8}, {Time: 5, Freq: 7}}
+				ga.SpaceTime = 3
+				ga.BoundTime = bt
+				ga.BoundFreq = bf
+				ga.NThreads = nThr
+				var flts, dir, ffr etensor.Float32
+				ga.RenderFilters(&flts)
+				oshp := ga.OutShape(2, steps, nMel)
+				dir.SetShape(oshp, nil, nil)
+				ffr.SetShape(oshp, nil, nil)
+				if err := Conv(1, ga, steps, &dir, nMel, &flts, mels); err != nil {
+					t.Fatalf("time %v freq %v: Conv error: %v", bt, bf, err)
+				}
+				ga.FFT = true
+				if err := Conv(1, ga, steps, &ffr, nMel, &flts, mels); err != nil {
+					t.Fatalf("time %v freq %v: FFT Conv error: %v", bt, bf, err)
+				}
+				for i, v := range ffr.Values {
+					if math.Abs(float64(v-dir.Values[i])) > 1e-4 {
+						t.Errorf("time %v freq %v threads %d: FFT value %d = %v, direct %v", bt, bf, nThr, i, v, dir.Values[i])
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkConv(b *testing.B) {
+	const steps, nMel = 200, 64
+	mels := testMels(steps+24, nMel, 1)
+	nCPU := runtime.NumCPU()
+	if nCPU < 2 {
+		nCPU = 2
+	}
+	for _, useFFT := range []bool{false, true} {
+		for _, nThr := range []int{1, nCPU} {
+			name := "Direct"
+			if useFFT {
+				name = "FFT"
+			}
+			b.Run(fmt.Sprintf("%s/NThreads%d", name, nThr), func(b *testing.B) {
+				var ga Params
+				ga.Defaults()
+				ga.Sizes = []Size{{Time: 6, Freq: 6}, {Time: 12, Freq: 12}, {Time: 24, Freq: 24}}
+				ga.NThreads = nThr
+				ga.FFT = useFFT
+				var flts, raw etensor.Float32
+				ga.RenderFilters(&flts)
+				raw.SetShape(ga.OutShape(1, steps, nMel), nil, nil)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					Conv(0, ga, steps, &raw, nMel, &flts, mels)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agabor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emer/etable/etensor"
+)
+
+// PoolType is the function used to combine the values within a pool
+type PoolType int32
+
+const (
+	// MaxPool takes the maximum value within the pool
+	MaxPool = iota
+
+	// MeanPool takes the average value within the pool
+	MeanPool
+
+	PoolTypeN
+)
+
+//go:generate stringer -type=PoolType
+
+// PoolParams are params for pooling gabor outputs over neighborhoods of frequency and time,
+// separately for each channel, scale, on / off polarity and filter
+type PoolParams struct {
+	On         bool     `desc:"pool the gabor outputs over frequency / time neighborhoods"`
+	Type       PoolType `viewif:"On" def:"MaxPool" desc:"how values within a pool are combined"`
+	SizeTime   int      `viewif:"On" def:"2" desc:"size of the pool in the time domain, in gabor output positions"`
+	SizeFreq   int      `viewif:"On" def:"2" desc:"size of the pool in the frequency domain, in gabor output positions"`
+	StrideTime int      `viewif:"On" def:"2" desc:"spacing between pools in the time domain -- equal to SizeTime for non-overlapping pools"`
+	StrideFreq int      `viewif:"On" def:"2" desc:"spacing between pools in the frequency domain -- equal to SizeFreq for non-overlapping pools"`
+}
+
+// Defaults sets non-overlapping 2 x 2 max pooling, which is off by default
+func (pp *PoolParams) Defaults() {
+	pp.On = false
+	pp.Type = MaxPool
+	pp.SizeTime = 2
+	pp.SizeFreq = 2
+	pp.StrideTime = 2
+	pp.StrideFreq = 2
+}
+
+// PoolSize returns the number of pools over n positions for the given pool size and stride --
+// the last pool may extend beyond the positions, in which case it only covers the remaining ones
+func PoolSize(n, size, stride int) int {
+	if n <= 0 || stride <= 0 {
+		return 0
+	}
+	if n <= size {
+		return 1
+	}
+	return ((n - size + stride - 1) / stride) + 1
+}
+
+// OutShape returns the pooled output shape for the given Conv output shape:
+// [channels][scales][freq][time][on/off][filters]
+func (pp *PoolParams) OutShape(inShape []int) []int {
+	oshp := append([]int{}, inShape...)
+	if pp.On {
+		oshp[2] = PoolSize(inShape[2], pp.SizeFreq, pp.StrideFreq)
+		oshp[3] = PoolSize(inShape[3], pp.SizeTime, pp.StrideTime)
+	}
+	return oshp
+}
+
+// Pool pools the Conv output in over frequency and time into out, which is shaped according to OutShape
+func (pp *PoolParams) Pool(in, out *etensor.Float32) error {
+	if in.NumDims() != 6 {
+		return fmt.Errorf("agabor.Pool: input shape %v is not a gabor output shape", in.Shapes())
+	}
+	if pp.SizeTime <= 0 || pp.SizeFreq <= 0 || pp.StrideTime <= 0 || pp.StrideFreq <= 0 {
+		return fmt.Errorf("agabor.Pool: pool sizes and strides must be positive")
+	}
+	ishp := in.Shapes()
+	oshp := pp.OutShape(ishp)
+	out.SetShape(oshp, nil, []string{"Chan", "Scale", "Freq", "Time", "Polarity", "Filter"})
+	nInner := ishp[4] * ishp[5]
+	ins := in.Values
+	outs := out.Values
+	for c := 0; c < ishp[0]; c++ {
+		for sc := 0; sc < ishp[1]; sc++ {
+			for pf := 0; pf < oshp[2]; pf++ {
+				fst := pf * pp.StrideFreq
+				fed := fst + pp.SizeFreq
+				if fed > ishp[2] {
+					fed = ishp[2]
+				}
+				for pt := 0; pt < oshp[3]; pt++ {
+					tst := pt * pp.StrideTime
+					ted := tst + pp.SizeTime
+					if ted > ishp[3] {
+						ted = ishp[3]
+					}
+					oi := out.Offset([]int{c, sc, pf, pt, 0, 0})
+					for ii := 0; ii < nInner; ii++ {
+						val := float32(0)
+						n := 0
+						for f := fst; f < fed; f++ {
+							for t := tst; t < ted; t++ {
+								iv := ins[in.Offset([]int{c, sc, f, t, 0, 0})+ii]
+								switch {
+								case pp.Type == MeanPool:
+									val += iv
+								case n == 0 || iv > val:
+									val = iv
+								}
+								n++
+							}
+						}
+						if pp.Type == MeanPool && n > 0 {
+							val /= float32(n)
+						}
+						outs[oi+ii] = val
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// KWTAParams are params for k-winners-take-all inhibitory competition among the gabor outputs
+// within each group of units -- a group is all the on / off x filters values at a given channel,
+// scale, frequency and time position, which is the unit group of a network input layer
+type KWTAParams struct {
+	On   bool    `desc:"apply k-winners-take-all competition within each group"`
+	K    int     `viewif:"On" def:"2" desc:"number of values within each group that remain active -- all other values are set to zero"`
+	Pt   float32 `viewif:"On" def:"0.25" min:"0" max:"1" desc:"inhibitory threshold is placed this proportion of the way from the k+1th to the kth highest value in the group -- this threshold is subtracted from all values, so the kth value retains 1-Pt of its margin over the k+1th"`
+	Gain float32 `viewif:"On" def:"1" desc:"multiplier applied to the values after subtracting the threshold"`
+}
+
+// Defaults sets the default kWTA params, which is off by default
+func (kp *KWTAParams) Defaults() {
+	kp.On = false
+	kp.K = 2
+	kp.Pt = 0.25
+	kp.Gain = 1
+}
+
+// KWTA applies the k-winners-take-all competition in place on the values of tsr,
+// which has the gabor output shape: [channels][scales][freq][time][on/off][filters]
+func (kp *KWTAParams) KWTA(tsr *etensor.Float32) error {
+	if tsr.NumDims() != 6 {
+		return fmt.Errorf("agabor.KWTA: shape %v is not a gabor output shape", tsr.Shapes())
+	}
+	if kp.K <= 0 {
+		return fmt.Errorf("agabor.KWTA: K must be positive: %v", kp.K)
+	}
+	grpSz := tsr.Dim(4) * tsr.Dim(5)
+	if kp.K >= grpSz { // everyone wins, with no threshold
+		for i, v := range tsr.Values {
+			tsr.Values[i] = kp.Gain * v
+		}
+		return nil
+	}
+	srt := make([]float32, grpSz)
+	for gi := 0; gi < len(tsr.Values); gi += grpSz {
+		grp := tsr.Values[gi : gi+grpSz]
+		copy(srt, grp)
+		sort.Slice(srt, func(i, j int) bool { return srt[i] > srt[j] })
+		thr := srt[kp.K] + kp.Pt*(srt[kp.K-1]-srt[kp.K])
+		for i, v := range grp {
+			v -= thr
+			if v < 0 {
+				v = 0
+			}
+			grp[i] = kp.Gain * v
+		}
+	}
+	return nil
+}
+
+// PoolKWTA applies the Pool and KWTA stages, as configured, to the Conv output raw, producing out
+func (ga *Params) PoolKWTA(raw, out *etensor.Float32) error {
+	if ga.Pool.On {
+		err := ga.Pool.Pool(raw, out)
+		if err != nil {
+			return err
+		}
+	} else {
+		out.SetShape(raw.Shapes(), nil, nil)
+		copy(out.Values, raw.Values)
+	}
+	if ga.KWTA.On {
+		return ga.KWTA.KWTA(out)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package agabor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emer/etable/etensor"
+)
+
+func TestPoolSize(t *testing.T) {
+	tests := []struct {
+		n, size, stride, want int
+	}{
+		{8, 2, 2, 4},
+		{9, 2, 2, 5},
+		{8, 3, 1, 6},
+		{2, 4, 2, 1},
+		{0, 2, 2, 0},
+		{8, 2, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := PoolSize(tt.n, tt.size, tt.stride); got != tt.want {
+			t.Errorf("PoolSize(%d, %d, %d) = %d, want %d", tt.n, tt.size, tt.stride, got, tt.want)
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	// one channel, scale and inner value, 3 freq x 4 time positions
+	in := etensor.NewFloat32([]int{1, 1, 3, 4, 1, 1}, nil, nil)
+	for i := range in.Values {
+		in.Values[i] = float32(i) // freq f, time t = 4*f + t
+	}
+	var pp PoolParams
+	pp.Defaults()
+	pp.On = true
+	var out etensor.Float32
+	if err := pp.Pool(in, &out); err != nil {
+		t.Fatalf("Pool error: %v", err)
+	}
+	want := []float32{5, 7, 9, 11} // the last freq pool only covers freq 2
+	if out.Dim(2) != 2 || out.Dim(3) != 2 {
+		t.Fatalf("pooled shape = %v, want 2 x 2 freq x time", out.Shapes())
+	}
+	for i, w := range want {
+		if out.Values[i] != w {
+			t.Errorf("max pool %d = %v, want %v", i, out.Values[i], w)
+		}
+	}
+	pp.Type = MeanPool
+	if err := pp.Pool(in, &out); err != nil {
+		t.Fatalf("Pool error: %v", err)
+	}
+	want = []float32{2.5, 4.5, 8.5, 10.5}
+	for i, w := range want {
+		if out.Values[i] != w {
+			t.Errorf("mean pool %d = %v, want %v", i, out.Values[i], w)
+		}
+	}
+	pp.StrideTime = 0
+	if err := pp.Pool(in, &out); err == nil {
+		t.Errorf("Pool with zero stride: no error")
+	}
+}
+
+func TestKWTA(t *testing.T) {
+	// one group of 2 on / off x 3 filters
+	tsr := etensor.NewFloat32([]int{1, 1, 1, 1, 2, 3}, nil, nil)
+	copy(tsr.Values, []float32{1, 6, 3, 2, 5, 4})
+	var kp KWTAParams
+	kp.Defaults()
+	kp.On = true
+	kp.Gain = 2
+	if err := kp.KWTA(tsr); err != nil {
+		t.Fatalf("KWTA error: %v", err)
+	}
+	// threshold is 4 + .25 * (5 - 4)
+	want := []float32{0, 3.5, 0, 0, 1.5, 0}
+	for i, w := range want {
+		if math.Abs(float64(tsr.Values[i]-w)) > 1e-6 {
+			t.Errorf("KWTA value %d = %v, want %v", i, tsr.Values[i], w)
+		}
+	}
+
+	copy(tsr.Values, []float32{1, 6, 3, 2, 5, 4})
+	kp.K = 6
+	if err := kp.KWTA(tsr); err != nil {
+		t.Fatalf("KWTA error: %v", err)
+	}
+	want = []float32{2, 12, 6, 4, 10, 8}
+	for i, w := range want {
+		if tsr.Values[i] != w {
+			t.Errorf("KWTA with K >= group size value %d = %v, want %v", i, tsr.Values[i], w)
+		}
+	}
+
+	kp.K = 0
+	if err := kp.KWTA(tsr); err == nil {
+		t.Errorf("KWTA with K = 0: no error")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/emer/auditory/dft"
 	"github.com/emer/auditory/mel"
 	"github.com/emer/auditory/sound"
+	"github.com/emer/auditory/strf"
 	"github.com/emer/etable/etensor"
 	_ "github.com/emer/etable/etview" // include to get gui views
 	"github.com/goki/gi/gi"
@@ -51,6 +52,10 @@ type Aud struct {
 	Gabor           agabor.Params     `viewif:"FBank.On" desc:" full set of frequency / time gabor filters -- first size"`
 	GaborFilters    etensor.Float32   `viewif:"On=true" desc:"full gabor filters"`
 	GaborTsr        etensor.Float32   `view:"no-inline" desc:" raw output of Gabor -- full segment's worth of gabor steps"`
+	GaborPoolTsr    etensor.Float32   `view:"no-inline" desc:" pooled and kWTA output of Gabor, if Gabor.Pool or Gabor.KWTA are on"`
+	Strf            strf.Params       `viewif:"FBank.On" desc:" spectro-temporal modulation filters, tuned to temporal modulation rates and spectral modulation scales"`
+	StrfFilters     etensor.Float32   `viewif:"On=true" desc:"spectro-temporal modulation filters"`
+	StrfTsrs        []etensor.Float32 `view:"no-inline" desc:" output of Strf for each channel -- full segment's worth of steps"`
 	Segment         int               `inactive:"+" desc:" the current segment (i.e. one segments worth of samples) - zero is first segment"`
 	FftCoefs        []complex128      `view:"-" desc:" discrete fourier transform (fft) output complex representation"`
 	Fft             *fourier.CmplxFFT `view:"-" desc:" struct for fast fourier transform"`
@@ -110,15 +115,28 @@ func (aud *Aud) Config() {
 
 	aud.Gabor.On = true
 	if aud.Gabor.On {
-		aud.Gabor.Defaults(aud.SndProcess.Derived.SegmentSteps, aud.Mel.FBank.NFilters)
-		aud.GaborFilters.SetShape([]int{aud.Gabor.NFilters, aud.Gabor.SizeFreq, aud.Gabor.SizeTime}, nil, nil)
+		aud.Gabor.Defaults()
+		// override any default Gabor values here (e.g. Sizes, Angles) - then call RenderFilters
 		aud.Gabor.RenderFilters(&aud.GaborFilters)
-		tsrX := ((aud.SndProcess.Derived.SegmentSteps - 1) / aud.Gabor.SpaceTime) + 1
-		tsrY := ((aud.Mel.FBank.NFilters - aud.Gabor.SizeFreq - 1) / aud.Gabor.SpaceFreq) + 1
-		aud.GaborTsr.SetShape([]int{aud.Sound.Channels(), tsrY, tsrX, 2, aud.Gabor.NFilters}, nil, nil)
+		aud.GaborTsr.SetShape(aud.Gabor.OutShape(aud.Sound.Channels(), aud.SndProcess.Derived.SegmentSteps, aud.Mel.FBank.NFilters), nil, nil)
 		aud.GaborTsr.SetMetaData("odd-row", "true")
 		aud.GaborTsr.SetMetaData("grid-fill", ".9")
 	}
+
+	aud.Strf.On = false // off by default -- set to true to also apply the spectro-temporal modulation filters
+	if aud.Strf.On {
+		aud.Strf.Defaults()
+		// override any default Strf values here (e.g. Rates, Scales) - then call Config and RenderFilters
+		aud.Strf.Config(aud.SndProcess.Params.StepMs, &aud.Mel.FBank)
+		err := aud.Strf.RenderFilters(&aud.StrfFilters)
+		if err != nil {
+			log.Println(err)
+		}
+		aud.StrfTsrs = make([]etensor.Float32, aud.Sound.Channels())
+		for ch := range aud.StrfTsrs {
+			aud.StrfTsrs[ch].SetShape(aud.Strf.OutShape(aud.SndProcess.Derived.SegmentSteps, aud.Mel.FBank.NFilters), nil, nil)
+		}
+	}
 }
 
 // Initialize sets all the tensor result data to zeros
@@ -142,7 +160,7 @@ func (aud *Aud) LoadSound(snd *sound.Wave) {
 }
 
 // ProcessSoundFile loads a sound from file and intializes for a new sound
-// if the sound is more than one segment long call ProcessSegment followed by ApplyGabor for each segment beyond the first
+// if the sound is more than one segment long call ProcessSegment followed by ApplyGabor and ApplyStrf for each segment beyond the first
 func (aud *Aud) ProcessSoundFile(fn string) {
 	aud.PrevSndFile = string(aud.CurSndFile)
 	aud.CurSndFile = gi.FileName(fn)
@@ -156,6 +174,7 @@ func (aud *Aud) ProcessSoundFile(fn string) {
 	aud.SndProcess.Pad(aud.Signal.Values)
 	aud.ProcessSegment()
 	aud.ApplyGabor()
+	aud.ApplyStrf()
 	aud.ToolBar.UpdateActions()
 }
 
@@ -200,7 +219,30 @@ func (aud *Aud) ProcessStep(ch, step int) bool {
 func (aud *Aud) ApplyGabor() {
 	if aud.Gabor.On {
 		for ch := int(0); ch < aud.Sound.Channels(); ch++ {
-			agabor.Conv(ch, aud.Gabor, aud.SndProcess.Derived.SegmentStepsPlus, &aud.GaborTsr, aud.Mel.FBank.NFilters, &aud.GaborFilters, &aud.MelFBankSegment)
+			err := agabor.Conv(ch, aud.Gabor, aud.SndProcess.Derived.SegmentSteps, &aud.GaborTsr, aud.Mel.FBank.NFilters, &aud.GaborFilters, &aud.MelFBankSegment)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+		}
+		if aud.Gabor.Pool.On || aud.Gabor.KWTA.On {
+			err := aud.Gabor.PoolKWTA(&aud.GaborTsr, &aud.GaborPoolTsr)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// ApplyStrf convolves the spectro-temporal modulation filters with the mel output
+func (aud *Aud) ApplyStrf() {
+	if aud.Strf.On {
+		for ch := int(0); ch < aud.Sound.Channels(); ch++ {
+			err := aud.Strf.Conv(ch, aud.SndProcess.Derived.SegmentSteps, &aud.StrfFilters, &aud.MelFBankSegment, &aud.StrfTsrs[ch])
+			if err != nil {
+				log.Println(err)
+				return
+			}
 		}
 	}
 }
@@ -256,6 +298,7 @@ func (aud *Aud) ConfigGui() *gi.Window {
 	}}, win.This(), func(recv, send ki.Ki, sig int64, data interface{}) {
 		aud.ProcessSegment()
 		aud.ApplyGabor()
+		aud.ApplyStrf()
 		vp.FullRender2DTree()
 	})
 
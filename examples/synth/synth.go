@@ -5,6 +5,8 @@
 package main
 
 import (
+	"log"
+
 	"github.com/emer/auditory/trm"
 	_ "github.com/emer/etable/etview" // include to get gui views
 	"github.com/goki/gi/gi"
@@ -81,12 +83,24 @@ var TheSyn Synth
 
 func mainrun() {
 	TheSyn.Defaults()
-	TheSyn.vc.Voice.Init()
-	TheSyn.vc.Init()
+	TheSyn.vc.Voice.Init() // the Female voice -- vc.Init uses the Male voice if none is set
+	err := TheSyn.vc.Init()
+	if err != nil {
+		log.Println(err)
+	}
 	TheSyn.vc.Initialize()
-	TheSyn.vc.LoadEnglishPhones()
-	TheSyn.vc.InitSynth()
-	TheSyn.vc.SynthPhones("ee", true, true)
+	err = TheSyn.vc.LoadEnglishPhones()
+	if err != nil {
+		log.Println(err)
+	}
+	err = TheSyn.vc.InitSynth()
+	if err != nil {
+		log.Println(err)
+	}
+	err = TheSyn.vc.SynthPhones("ee", true, true)
+	if err != nil {
+		log.Println(err)
+	}
 
 	win := TheSyn.ConfigGui()
 	win.StartEventLoop()
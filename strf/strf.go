@@ -0,0 +1,252 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package strf provides a bank of spectro-temporal modulation filters (STRFs),
+// as used in models of auditory cortex: each filter is tuned to a temporal modulation
+// rate (in Hz) and a spectral modulation scale (in cycles / octave), and is a 2d Gabor
+// with a Hann window envelope spanning a fixed number of modulation cycles in each dimension.
+package strf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chewxy/math32"
+	"github.com/emer/auditory/mel"
+	"github.com/emer/etable/etensor"
+)
+
+// Params for the spectro-temporal modulation filter bank -- filters are rendered in
+// physical units, so Config must be called with the step size and mel filter bank
+// parameters of the input before rendering
+type Params struct {
+	On          bool      `desc:"use this spectro-temporal modulation filtering of the mel filter bank output"`
+	Rates       []float32 `viewif:"On" def:"-16,-8,-4,0,4,8,16" desc:"temporal modulation rates in Hz -- positive rates respond to upward frequency sweeps and negative rates to downward sweeps -- 0 is a purely spectral filter"`
+	Scales      []float32 `viewif:"On" def:"0,0.25,0.5,1,2" desc:"spectral modulation scales in cycles / octave -- 0 is a purely temporal filter"`
+	CyclesTime  float32   `viewif:"On" def:"3.5" desc:"number of modulation periods spanned by the temporal envelope of each filter -- determines the temporal extent of the filter for a given rate"`
+	CyclesFreq  float32   `viewif:"On" def:"3.5" desc:"number of modulation periods spanned by the spectral envelope of each filter -- determines the spectral extent of the filter for a given scale"`
+	MaxSizeTime int       `viewif:"On" def:"40" desc:"maximum filter size in time steps -- envelopes of low rates that would be longer than this are truncated"`
+	MaxSizeFreq int       `viewif:"On" def:"32" desc:"maximum filter size in mel filter channels -- envelopes of low scales that would be wider than this are truncated"`
+	Mag         bool      `viewif:"On" def:"true" desc:"output the magnitude of the quadrature pair (cosine and sine) responses -- otherwise output the signed cosine response"`
+	Gain        float32   `viewif:"On" def:"1" desc:"overall gain multiplier applied to the filter outputs"`
+	StepMs      float32   `inactive:"+" desc:"duration of one time step of the input in milliseconds -- set by Config"`
+	ChanOct     float32   `inactive:"+" desc:"average spacing of the mel filter channels in octaves -- set by Config"`
+	SizeTime    int       `inactive:"+" desc:"time size of the rendered filters tensor -- max over all rates -- set by RenderFilters"`
+	SizeFreq    int       `inactive:"+" desc:"frequency size of the rendered filters tensor -- max over all scales -- set by RenderFilters"`
+}
+
+// Defaults initializes the params with a bank of 7 rates x 5 scales
+func (sf *Params) Defaults() {
+	sf.On = true
+	sf.Rates = []float32{-16, -8, -4, 0, 4, 8, 16}
+	sf.Scales = []float32{0, 0.25, 0.5, 1, 2}
+	sf.CyclesTime = 3.5
+	sf.CyclesFreq = 3.5
+	sf.MaxSizeTime = 40
+	sf.MaxSizeFreq = 32
+	sf.Mag = true
+	sf.Gain = 1
+}
+
+// Config sets the physical units of the input: the step size in milliseconds (sound.Params.StepMs)
+// and the spacing of the mel filter channels, which is computed as the average number of octaves
+// between the center frequencies of adjacent filters
+func (sf *Params) Config(stepMs float32, fb *mel.FilterBank) {
+	sf.StepMs = stepMs
+	hiMel := mel.FreqToMel(fb.HiHz)
+	loMel := mel.FreqToMel(fb.LoHz)
+	melIncr := (hiMel - loMel) / float32(fb.NFilters+1)
+	loCtr := mel.MelToFreq(loMel + melIncr)
+	hiCtr := mel.MelToFreq(loMel + float32(fb.NFilters)*melIncr)
+	if fb.NFilters > 1 {
+		sf.ChanOct = math32.Log2(hiCtr/loCtr) / float32(fb.NFilters-1)
+	} else {
+		sf.ChanOct = math32.Log2(fb.HiHz / fb.LoHz)
+	}
+}
+
+// FilterSize returns the number of samples covered by the envelope for the given
+// modulation frequency, in units of cycles per sample, limited to max -- 0 frequency is a size of 1
+func FilterSize(freq, cycles float32, max int) int {
+	freq = math32.Abs(freq)
+	if freq == 0 {
+		return 1
+	}
+	sz := int(math32.Round(cycles / freq))
+	if sz < 1 {
+		sz = 1
+	}
+	if sz > max {
+		sz = max
+	}
+	return sz
+}
+
+// RateSteps returns the temporal modulation rate in cycles per time step for given rate in Hz
+func (sf *Params) RateSteps(rate float32) float32 {
+	return rate * sf.StepMs / 1000.0
+}
+
+// ScaleChans returns the spectral modulation scale in cycles per mel channel for given scale in cycles / octave
+func (sf *Params) ScaleChans(scale float32) float32 {
+	return scale * sf.ChanOct
+}
+
+// RenderFilters generates the filters into the given tensor, which is shaped as:
+// [rates][scales][2][SizeTime][SizeFreq], where the 2 are the cosine and sine (quadrature) filters --
+// filters smaller than the overall size are centered within it and zero elsewhere
+func (sf *Params) RenderFilters(filters *etensor.Float32) error {
+	if sf.StepMs <= 0 || sf.ChanOct <= 0 {
+		return errors.New("strf.RenderFilters: Config must be called before rendering filters")
+	}
+	sf.SizeTime = 1
+	for _, rt := range sf.Rates {
+		sz := FilterSize(sf.RateSteps(rt), sf.CyclesTime, sf.MaxSizeTime)
+		if sz > sf.SizeTime {
+			sf.SizeTime = sz
+		}
+	}
+	sf.SizeFreq = 1
+	for _, sc := range sf.Scales {
+		sz := FilterSize(sf.ScaleChans(sc), sf.CyclesFreq, sf.MaxSizeFreq)
+		if sz > sf.SizeFreq {
+			sf.SizeFreq = sz
+		}
+	}
+	filters.SetShape([]int{len(sf.Rates), len(sf.Scales), 2, sf.SizeTime, sf.SizeFreq}, nil, []string{"Rate", "Scale", "Phase", "Time", "Freq"})
+	filters.SetZeros()
+	for ri, rt := range sf.Rates {
+		for si, sc := range sf.Scales {
+			sf.RenderFilter(filters, ri, si, sf.RateSteps(rt), sf.ScaleChans(sc))
+		}
+	}
+	return nil
+}
+
+// RenderFilter generates the cosine and sine filters for one rate and scale,
+// given in cycles per time step and cycles per channel
+func (sf *Params) RenderFilter(filters *etensor.Float32, ri, si int, rate, scale float32) {
+	szT := FilterSize(rate, sf.CyclesTime, sf.MaxSizeTime)
+	szF := FilterSize(scale, sf.CyclesFreq, sf.MaxSizeFreq)
+	offT := (sf.SizeTime - szT) / 2
+	offF := (sf.SizeFreq - szF) / 2
+	ctrT := float32(szT-1) / 2.0
+	ctrF := float32(szF-1) / 2.0
+
+	// hann window envelope, and its sum for removing the dc component and normalizing
+	env := make([]float32, szT*szF)
+	envSum := float32(0)
+	for t := 0; t < szT; t++ {
+		for f := 0; f < szF; f++ {
+			e := Hann(t, szT) * Hann(f, szF)
+			env[t*szF+f] = e
+			envSum += e
+		}
+	}
+	dc := rate != 0 || scale != 0
+	for ph := 0; ph < 2; ph++ {
+		phOff := float32(ph) * (-math32.Pi / 2.0) // sin = cos(x - pi/2)
+		carSum := float32(0)
+		for t := 0; t < szT; t++ {
+			for f := 0; f < szF; f++ {
+				arg := 2.0*math32.Pi*(rate*(float32(t)-ctrT)-scale*(float32(f)-ctrF)) + phOff
+				carSum += env[t*szF+f] * math32.Cos(arg)
+			}
+		}
+		mean := float32(0)
+		if dc {
+			mean = carSum / envSum
+		}
+		for t := 0; t < szT; t++ {
+			for f := 0; f < szF; f++ {
+				arg := 2.0*math32.Pi*(rate*(float32(t)-ctrT)-scale*(float32(f)-ctrF)) + phOff
+				val := env[t*szF+f] * (math32.Cos(arg) - mean) / envSum
+				filters.Set([]int{ri, si, ph, offT + t, offF + f}, val)
+			}
+		}
+	}
+}
+
+// Hann returns the value of a Hann window of size n at index i, with nonzero end points
+func Hann(i, n int) float32 {
+	if n <= 1 {
+		return 1
+	}
+	return 0.5 - 0.5*math32.Cos(2.0*math32.Pi*float32(i+1)/float32(n+1))
+}
+
+// OutShape returns the shape of the Conv output tensor for the given number of steps and
+// mel filters: [rates][scales][steps][melFilters] -- the filters are applied at every time step
+// and mel channel
+func (sf *Params) OutShape(steps, melFilters int) []int {
+	return []int{len(sf.Rates), len(sf.Scales), steps, melFilters}
+}
+
+// Conv convolves the filters with one channel of the mel filter bank output of a segment
+// (MelFBankSegment, shaped [steps][melFilters][channels]) for the first steps time steps of
+// the segment -- the filters are centered on each time step and mel channel, and input outside
+// of the segment is zero. out must be shaped according to OutShape
+func (sf *Params) Conv(ch int, steps int, filters *etensor.Float32, melData *etensor.Float32, out *etensor.Float32) error {
+	nr := len(sf.Rates)
+	ns := len(sf.Scales)
+	if filters.NumDims() != 5 || filters.Dim(0) != nr || filters.Dim(1) != ns || filters.Dim(3) != sf.SizeTime || filters.Dim(4) != sf.SizeFreq {
+		return fmt.Errorf("strf.Conv: filters shape %v does not match params -- call RenderFilters", filters.Shapes())
+	}
+	if melData.NumDims() != 3 || melData.Dim(0) < steps || ch < 0 || ch >= melData.Dim(2) {
+		return fmt.Errorf("strf.Conv: mel data shape %v does not contain %v steps for channel %v", melData.Shapes(), steps, ch)
+	}
+	nMel := melData.Dim(1)
+	nch := melData.Dim(2)
+	oshp := sf.OutShape(steps, nMel)
+	if out.NumDims() != len(oshp) {
+		return fmt.Errorf("strf.Conv: output shape %v does not match expected shape %v", out.Shapes(), oshp)
+	}
+	for d := range oshp {
+		if out.Dim(d) != oshp[d] {
+			return fmt.Errorf("strf.Conv: output shape %v does not match expected shape %v", out.Shapes(), oshp)
+		}
+	}
+
+	szT := sf.SizeTime
+	szF := sf.SizeFreq
+	hT := szT / 2
+	hF := szF / 2
+	flts := filters.Values
+	mels := melData.Values
+	outs := out.Values
+	fltSz := szT * szF
+	for ri := 0; ri < nr; ri++ {
+		for si := 0; si < ns; si++ {
+			cst := ((ri*ns+si)*2 + 0) * fltSz
+			sst := ((ri*ns+si)*2 + 1) * fltSz
+			for t := 0; t < steps; t++ {
+				for f := 0; f < nMel; f++ {
+					cSum := float32(0)
+					sSum := float32(0)
+					for ft := 0; ft < szT; ft++ {
+						it := t + ft - hT
+						if it < 0 || it >= steps {
+							continue
+						}
+						for ff := 0; ff < szF; ff++ {
+							jf := f + ff - hF
+							if jf < 0 || jf >= nMel {
+								continue
+							}
+							iVal := mels[(it*nMel+jf)*nch+ch]
+							cSum += flts[cst+ft*szF+ff] * iVal
+							sSum += flts[sst+ft*szF+ff] * iVal
+						}
+					}
+					val := cSum
+					if sf.Mag {
+						val = math32.Sqrt(cSum*cSum + sSum*sSum)
+					}
+					outs[((ri*ns+si)*steps+t)*nMel+f] = sf.Gain * val
+				}
+			}
+		}
+	}
+	return nil
+}
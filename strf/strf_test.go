@@ -0,0 +1,105 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strf
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emer/auditory/mel"
+	"github.com/emer/etable/etensor"
+)
+
+// testParams returns default params configured for 10 ms steps and a 32 channel mel filter bank
+func testParams() *Params {
+	var sf Params
+	sf.Defaults()
+	fb := mel.FilterBank{NFilters: 32, LoHz: 120, HiHz: 10000}
+	sf.Config(10, &fb)
+	return &sf
+}
+
+func TestRenderFilters(t *testing.T) {
+	var sf Params
+	sf.Defaults()
+	var flts etensor.Float32
+	if err := sf.RenderFilters(&flts); err == nil {
+		t.Errorf("RenderFilters before Config: no error")
+	}
+	tsf := testParams()
+	if err := tsf.RenderFilters(&flts); err != nil {
+		t.Fatalf("RenderFilters error: %v", err)
+	}
+	want := []int{len(tsf.Rates), len(tsf.Scales), 2, tsf.SizeTime, tsf.SizeFreq}
+	for d, n := range want {
+		if flts.Dim(d) != n {
+			t.Fatalf("filters shape = %v, want %v", flts.Shapes(), want)
+		}
+	}
+	// all but the 0 rate, 0 scale filter have no dc component
+	fltSz := tsf.SizeTime * tsf.SizeFreq
+	for ri, rt := range tsf.Rates {
+		for si, sc := range tsf.Scales {
+			if rt == 0 && sc == 0 {
+				continue
+			}
+			st := ((ri*len(tsf.Scales)+si)*2 + 0) * fltSz
+			sum := 0.0
+			for _, v := range flts.Values[st : st+fltSz] {
+				sum += float64(v)
+			}
+			if math.Abs(sum) > 1e-5 {
+				t.Errorf("rate %v scale %v: cosine filter sum = %v, want 0", rt, sc, sum)
+			}
+		}
+	}
+}
+
+func TestConv(t *testing.T) {
+	sf := testParams()
+	var flts etensor.Float32
+	if err := sf.RenderFilters(&flts); err != nil {
+		t.Fatalf("RenderFilters error: %v", err)
+	}
+	const steps, nMel = 60, 32
+	// a ripple moving up in frequency at 8 Hz and 1 cycle / octave
+	rate := sf.RateSteps(8)
+	scale := sf.ScaleChans(1)
+	mels := etensor.NewFloat32([]int{steps, nMel, 2}, nil, nil)
+	for st := 0; st < steps; st++ {
+		for f := 0; f < nMel; f++ {
+			mels.Set([]int{st, f, 1}, float32(math.Cos(2*math.Pi*float64(rate*float32(st)-scale*float32(f)))))
+		}
+	}
+	var out etensor.Float32
+	out.SetShape(sf.OutShape(steps, nMel), nil, nil)
+	if err := sf.Conv(1, steps, &flts, mels, &out); err != nil {
+		t.Fatalf("Conv error: %v", err)
+	}
+	want := []int{len(sf.Rates), len(sf.Scales), steps, nMel}
+	for d, n := range want {
+		if out.Dim(d) != n {
+			t.Fatalf("output shape = %v, want %v", out.Shapes(), want)
+		}
+	}
+	ri, rn, si := 5, 1, 3 // rates 8 and -8, scale 1
+	up := out.Value([]int{ri, si, steps / 2, nMel / 2})
+	down := out.Value([]int{rn, si, steps / 2, nMel / 2})
+	if !(up > 2*down) {
+		t.Errorf("response to an upward ripple of the upward filter %v is not much larger than that of the downward filter %v", up, down)
+	}
+
+	var bad etensor.Float32
+	bad.SetShape([]int{len(sf.Rates), len(sf.Scales), steps, nMel - 1}, nil, nil)
+	if err := sf.Conv(1, steps, &flts, mels, &bad); err == nil {
+		t.Errorf("Conv with wrong output shape: no error")
+	}
+	if err := sf.Conv(2, steps, &flts, mels, &out); err == nil {
+		t.Errorf("Conv with channel out of range: no error")
+	}
+	if err := sf.Conv(1, steps+1, &flts, mels, &out); err == nil {
+		t.Errorf("Conv with more steps than the mel data: no error")
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// TransferMaxFreq is the default maximum frequency in Hz of the transfer function computed by AnalyzeTract
+const TransferMaxFreq = 5000
+
+// TransferNFreqs is the default number of frequencies at which the transfer function is computed by AnalyzeTract
+const TransferNFreqs = 1000
+
+// oroSectRegion is the region of each section of the 10 section model
+var oroSectRegion = [OroPharynxSectCount]int{OroPharynxReg1, OroPharynxReg2, OroPharynxReg3, OroPharynxReg4, OroPharynxReg4,
+	OroPharynxReg5, OroPharynxReg5, OroPharynxReg6, OroPharynxReg7, OroPharynxReg8}
+
+// AreaFunc is the area function of the vocal tract: the cross-sectional area of each of its sections,
+// which are all of the same length, for the oropharynx from the glottis to the lips, and for the nasal
+// tract from the velum to the nostrils
+type AreaFunc struct {
+	SectLen    float32   // length of each section in cm
+	Areas      []float32 // area of each section of the oropharynx in cm^2, from the glottis to the lips
+	VelumSect  int       // section of the oropharynx at the start of which the nasal tract branches off
+	NasalAreas []float32 // area of each section of the nasal tract in cm^2, from the velum to the nostrils
+}
+
+// RegionRadii returns the radius of each region of the oropharynx in cm for the control params and voice,
+// as synthesized: Radius1 of the voice for region 1 and the control Radii for regions 2-8, scaled by RadiusCoef
+func RegionRadii(ctrl *VocalTractCtrl, voice *VoiceParams) [OroPharynxRegCount]float32 {
+	var radii [OroPharynxRegCount]float32
+	radii[0] = voice.Radius1 * voice.RadiusCoef
+	for i := 1; i < OroPharynxRegCount; i++ {
+		radii[i] = ctrl.Radii[i-1] * voice.RadiusCoef
+	}
+	for i, r := range radii {
+		if r < GsTrmTubeMinRadius {
+			radii[i] = GsTrmTubeMinRadius
+		}
+	}
+	return radii
+}
+
+// NewAreaFunc returns the area function for the control params and voice, for the 10 section model
+// if nSects is 0, or else for the Waveguide of nSects sections (see WaveguideSectCount), with radii
+// interpolated from the regions, and its nasal tract of sections of the same length -- returns
+// ErrTractLength if the TractLength of the voice is not positive
+func NewAreaFunc(ctrl *VocalTractCtrl, voice *VoiceParams, nSects int) (*AreaFunc, error) {
+	if !(voice.TractLength > 0) {
+		return nil, ErrTractLength
+	}
+	radii := RegionRadii(ctrl, voice)
+	var sectRadii, nasalRadii []float32
+	if nSects > 0 {
+		var wg Waveguide
+		wg.Init(nSects)
+		wg.InterpRadii(&radii)
+		wg.SetNasalRadii(ctrl.Velum, voice)
+		sectRadii = wg.SectRadii
+		nasalRadii = wg.NasalRadii
+	} else {
+		sectRadii = make([]float32, OroPharynxSectCount)
+		for i, r := range oroSectRegion {
+			sectRadii[i] = radii[r]
+		}
+		nasalRadii = make([]float32, NasalTractSectCount)
+		nasalRadii[0] = ctrl.Velum
+		for i := 1; i < NasalTractSectCount; i++ {
+			nasalRadii[i] = voice.NoseRadiusVal(i - 1)
+		}
+	}
+	n := len(sectRadii)
+	af := &AreaFunc{}
+	af.SectLen = voice.TractLength / float32(n)
+	af.VelumSect = int(math.Round(VelumPos * float64(n)))
+	af.Areas = make([]float32, n)
+	for i, r := range sectRadii {
+		af.Areas[i] = circleArea(r)
+	}
+	af.NasalAreas = make([]float32, len(nasalRadii))
+	for i, r := range nasalRadii {
+		af.NasalAreas[i] = circleArea(r)
+	}
+	return af, nil
+}
+
+// circleArea returns the area of a circle of radius r, limited to that of GsTrmTubeMinRadius
+func circleArea(r float32) float32 {
+	if r < GsTrmTubeMinRadius {
+		r = GsTrmTubeMinRadius
+	}
+	return math.Pi * r * r
+}
+
+// Length returns the length of the oropharynx in cm
+func (af *AreaFunc) Length() float32 {
+	return af.SectLen * float32(len(af.Areas))
+}
+
+// Formant is a resonance of the vocal tract
+type Formant struct {
+	Freq float32 // center frequency in Hz
+	BW   float32 // bandwidth in Hz, between the points 3 dB below the peak -- 0 if the gain does not fall 3 dB on both sides before the neighboring peaks
+}
+
+// TransferFunc is the transfer function of the vocal tract, from the volume velocity at the glottis
+// to the summed volume velocity at the lips and nostrils, and the formants found from it
+type TransferFunc struct {
+	Freqs    []float32 // frequencies in Hz
+	Gains    []float32 // gain in dB at each frequency
+	Formants []Formant // the peaks of the gain, in order of frequency
+}
+
+// TransferFunc computes the transfer function of the area function at nFreqs frequencies evenly spaced
+// up to maxFreq, treating each section as a lossy uniform tube, with the glottis closed and radiation at the
+// lips and nostrils from a piston in an infinite baffle -- temp is the temperature in degrees C (as in
+// VocalTractConfig.Temp) and loss the percent loss of amplitude per section (as in VocalTractConfig.Loss)
+func (af *AreaFunc) TransferFunc(temp, loss, maxFreq float32, nFreqs int) *TransferFunc {
+	tf := &TransferFunc{}
+	tf.Freqs = make([]float32, nFreqs)
+	tf.Gains = make([]float32, nFreqs)
+	c := float64(SpeedOfSound(temp)) * 100 // cm / sec
+	l := float64(af.SectLen)
+	alpha := 0.0 // attenuation per cm
+	if loss > 0 && loss < 100 {
+		alpha = -math.Log(1-float64(loss)/100) / l
+	}
+	for i := range tf.Freqs {
+		f := float64(i+1) * float64(maxFreq) / float64(nFreqs) // 0 is excluded, where a lossless nasal tract has no pressure
+		k := 2 * math.Pi * f / c
+		gl := complex(alpha*l, k*l)
+		ch, sh := cmplx.Cosh(gl), cmplx.Sinh(gl)
+
+		// pressure and volume velocity at the velum, for a unit volume velocity at the nostrils
+		na := af.NasalAreas
+		pv, uv := tubeChain(na, len(na)-1, 0, radiationImpedance(na[len(na)-1], k), 1, ch, sh)
+
+		// back from a unit volume velocity at the lips to the velum, where the nasal tract draws
+		// p / zn, and on to the glottis
+		a := af.Areas
+		p, u := tubeChain(a, len(a)-1, af.VelumSect, radiationImpedance(a[len(a)-1], k), 1, ch, sh)
+		nasal := p / pv // volume velocity at the nostrils
+		u += p * uv / pv
+		_, ug := tubeChain(a, af.VelumSect-1, 0, p, u, ch, sh)
+
+		tf.Freqs[i] = float32(f)
+		tf.Gains[i] = float32(20 * math.Log10(cmplx.Abs((1+nasal)/ug)))
+	}
+	tf.FindFormants()
+	return tf
+}
+
+// tubeChain returns the pressure and volume velocity at the start of section to of the tubes of given areas,
+// given the pressure p and volume velocity u at the end of section from, going back through the sections, each
+// with the cosh and sinh of its propagation constant times its length -- in units in which air has an impedance of 1
+func tubeChain(areas []float32, from, to int, p, u, ch, sh complex128) (complex128, complex128) {
+	for i := from; i >= to; i-- {
+		z0 := complex(1/float64(areas[i]), 0)
+		p, u = ch*p+z0*sh*u, sh*p/z0+ch*u
+	}
+	return p, u
+}
+
+// radiationImpedance returns the radiation impedance of a piston of given area in an infinite baffle,
+// at wavenumber k, in units in which air has an impedance of 1
+func radiationImpedance(area float32, k float64) complex128 {
+	a := math.Sqrt(float64(area) / math.Pi)
+	ka := k * a
+	return complex(ka*ka/2, 8*ka/(3*math.Pi)) / complex(float64(area), 0)
+}
+
+// FindFormants sets the Formants from the peaks of the Gains, with the frequency of each peak
+// refined by parabolic interpolation, and its bandwidth from the points 3 dB below the peak
+func (tf *TransferFunc) FindFormants() {
+	tf.Formants = tf.Formants[:0]
+	g := tf.Gains
+	for i := 1; i < len(g)-1; i++ {
+		if !(g[i] > g[i-1] && g[i] >= g[i+1]) {
+			continue
+		}
+		df := tf.Freqs[1] - tf.Freqs[0]
+		fm := Formant{Freq: tf.Freqs[i]}
+		if den := g[i-1] - 2*g[i] + g[i+1]; den != 0 {
+			fm.Freq += 0.5 * df * (g[i-1] - g[i+1]) / den
+		}
+		lo, hi := tf.crossing(i, -1, g[i]-3), tf.crossing(i, 1, g[i]-3)
+		if lo > 0 && hi > 0 {
+			fm.BW = hi - lo
+		}
+		tf.Formants = append(tf.Formants, fm)
+	}
+}
+
+// crossing returns the frequency, interpolated linearly, at which the gain first falls to g going from
+// index i in direction dir (-1 or 1) -- 0 if it rises again, or reaches the end of the frequency range, first
+func (tf *TransferFunc) crossing(i, dir int, g float32) float32 {
+	for j := i + dir; j >= 0 && j < len(tf.Gains); j += dir {
+		if tf.Gains[j] > tf.Gains[j-dir] {
+			return 0
+		}
+		if tf.Gains[j] <= g {
+			p := j - dir
+			t := (tf.Gains[p] - g) / (tf.Gains[p] - tf.Gains[j])
+			return tf.Freqs[p] + t*(tf.Freqs[j]-tf.Freqs[p])
+		}
+	}
+	return 0
+}
+
+// AnalyzeTract returns the area function and the transfer function, up to TransferMaxFreq, of the vocal tract
+// with the control params and voice, using the NSects, Temp and Loss of the config -- returns the
+// NewAreaFunc error, if any
+func AnalyzeTract(ctrl *VocalTractCtrl, voice *VoiceParams, config *VocalTractConfig) (*AreaFunc, *TransferFunc, error) {
+	af, err := NewAreaFunc(ctrl, voice, config.NSects)
+	if err != nil {
+		return nil, nil, err
+	}
+	tf := af.TransferFunc(config.Temp, config.Loss, TransferMaxFreq, TransferNFreqs)
+	return af, tf, nil
+}
+
+// Analyze returns the area function and the transfer function of the vocal tract for the current
+// control params (CurControl) and Voice -- see AnalyzeTract
+func (vt *VocalTract) Analyze() (*AreaFunc, *TransferFunc, error) {
+	return AnalyzeTract(&vt.CurControl, &vt.Voice, &vt.Config)
+}
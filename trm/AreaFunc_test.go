@@ -0,0 +1,99 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+// uniformTract returns a vocal tract configured as a uniform tube of radius 1 cm and length 17.5 cm,
+// with the velum closed, for the model of nSects sections (0 for the 10 section model)
+func uniformTract(t *testing.T, nSects int) *VocalTract {
+	t.Helper()
+	vt := &VocalTract{}
+	vt.Config.Defaults()
+	vt.Config.NSects = nSects
+	if err := vt.Init(); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	vt.Voice.TractLength = 17.5
+	vt.Voice.Radius1 = 1
+	vt.Voice.RadiusCoef = 1
+	vt.CurControl.Init()
+	vt.CurControl.Velum = 0
+	return vt
+}
+
+func TestUniformTubeFormants(t *testing.T) {
+	for _, nSects := range []int{0, 20} {
+		vt := uniformTract(t, nSects)
+		af, tf, err := vt.Analyze()
+		if err != nil {
+			t.Fatalf("NSects %d: Analyze error: %v", nSects, err)
+		}
+		if l := af.Length(); math.Abs(float64(l)-17.5) > 1e-4 {
+			t.Errorf("NSects %d: tract length = %v, want 17.5", nSects, l)
+		}
+		if len(tf.Formants) < 3 {
+			t.Fatalf("NSects %d: formants = %v, want at least 3", nSects, tf.Formants)
+		}
+		// a tube closed at the glottis and open at the lips resonates at odd multiples of c / 4L,
+		// about 500, 1500 and 2500 Hz -- radiation from the lips lengthens the tube by 8a / 3pi
+		c := float64(SpeedOfSound(vt.Config.Temp)) * 100
+		lEff := 17.5 + 8/(3*math.Pi)
+		for n := 1; n <= 3; n++ {
+			fm := tf.Formants[n-1]
+			ideal := float64(2*n-1) * c / (4 * 17.5)
+			want := float64(2*n-1) * c / (4 * lEff)
+			if math.Abs(float64(fm.Freq)-want) > 0.01*want {
+				t.Errorf("NSects %d: F%d = %v Hz, want %v with the end correction", nSects, n, fm.Freq, want)
+			}
+			if math.Abs(float64(fm.Freq)-ideal) > 0.1*ideal {
+				t.Errorf("NSects %d: F%d = %v Hz, not within 10%% of %v", nSects, n, fm.Freq, ideal)
+			}
+			if !(fm.BW > 0 && fm.BW < 200) {
+				t.Errorf("NSects %d: F%d bandwidth = %v Hz, want between 0 and 200", nSects, n, fm.BW)
+			}
+		}
+	}
+}
+
+func TestVelumFormants(t *testing.T) {
+	vt := uniformTract(t, 0)
+	_, oral, err := vt.Analyze()
+	if err != nil {
+		t.Fatalf("Analyze error: %v", err)
+	}
+	vt.CurControl.Velum = 1
+	_, nasal, err := vt.Analyze()
+	if err != nil {
+		t.Fatalf("Analyze error: %v", err)
+	}
+	if !(len(nasal.Formants) > len(oral.Formants)) {
+		t.Errorf("%d formants with the velum open, not more than the %d with it closed", len(nasal.Formants), len(oral.Formants))
+	}
+}
+
+func TestFindFormants(t *testing.T) {
+	tf := &TransferFunc{}
+	for i := 0; i < 100; i++ {
+		f := float32(i * 10)
+		tf.Freqs = append(tf.Freqs, f)
+		// peaks of 10 dB at 300 and 700 Hz, falling 1 dB per 10 Hz
+		g := float32(10 - math.Min(math.Abs(float64(f-300)), math.Abs(float64(f-700)))/10)
+		tf.Gains = append(tf.Gains, g)
+	}
+	tf.FindFormants()
+	if len(tf.Formants) != 2 {
+		t.Fatalf("formants = %v, want 2", tf.Formants)
+	}
+	for i, want := range []float32{300, 700} {
+		fm := tf.Formants[i]
+		if math.Abs(float64(fm.Freq-want)) > 1e-3 || math.Abs(float64(fm.BW-60)) > 1e-3 {
+			t.Errorf("formant %d = %v Hz with bandwidth %v, want %v with 60", i, fm.Freq, fm.BW, want)
+		}
+	}
+}
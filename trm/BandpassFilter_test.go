@@ -0,0 +1,47 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+// sineGain returns the steady state amplitude of the response of filter to a unit sine of
+// frequency freq, at the sample rate sr
+func sineGain(filter func(float32) float32, freq, sr float64) float64 {
+	n := int(sr / 2) // .5 sec, the first half of which settles the filter
+	max := 0.0
+	for i := 0; i < n; i++ {
+		y := float64(filter(float32(math.Sin(2 * math.Pi * freq * float64(i) / sr))))
+		if i >= n/2 && math.Abs(y) > max {
+			max = math.Abs(y)
+		}
+	}
+	return max
+}
+
+func TestBandpassFilter(t *testing.T) {
+	const sr, bw, cf = 20000, 500, 2000
+	var bf BandpassFilter
+	bf.Update(sr, bw, cf)
+	tests := []struct {
+		freq   float64
+		lo, hi float64
+	}{
+		{cf, 0.95, 1.05},
+		{cf - bw/2, 0.65, 0.76}, // -3 dB at the band edges
+		{cf + bw/2, 0.65, 0.76},
+		{200, 0, 0.1},
+		{8000, 0, 0.1},
+	}
+	for _, tt := range tests {
+		bf.Reset()
+		g := sineGain(bf.Filter, tt.freq, sr)
+		if g < tt.lo || g > tt.hi {
+			t.Errorf("gain at %v Hz = %v, want between %v and %v", tt.freq, g, tt.lo, tt.hi)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CtrlParamNames are the names of the control parameters, in CtrlParamIdxs order
+var CtrlParamNames = [NCtrlParams]string{"GlotPitch", "GlotVol", "AspVol", "FricVol", "FricPos", "FricCf", "FricBw",
+	"Radius2", "Radius3", "Radius4", "Radius5", "Radius6", "Radius7", "Radius8", "Velum"}
+
+// ErrTractLength is returned when the synthesizer is initialized for a voice without a positive TractLength
+var ErrTractLength = errors.New("trm: voice TractLength must be positive")
+
+// CtrlCountError is returned when control parameters are set from fewer than NCtrlParams values
+type CtrlCountError struct {
+	N int // number of values given
+}
+
+func (e *CtrlCountError) Error() string {
+	return fmt.Sprintf("trm: %d control values given, need %d", e.N, NCtrlParams)
+}
+
+// CtrlIdxError is returned when a control parameter is given by an index that is not a valid CtrlParamIdxs
+type CtrlIdxError struct {
+	Idx CtrlParamIdxs // the invalid index
+}
+
+func (e *CtrlIdxError) Error() string {
+	return fmt.Sprintf("trm: control param index %d is not valid, must be 0 - %d", e.Idx, NCtrlParams-1)
+}
+
+// CtrlRangeError is returned when a control parameter is outside of its range, or is not a number
+type CtrlRangeError struct {
+	Param CtrlParamIdxs // the parameter out of range
+	Val   float32       // its value
+	Min   float32       // minimum of its range
+	Max   float32       // maximum of its range
+}
+
+func (e *CtrlRangeError) Error() string {
+	return fmt.Sprintf("trm: control param %s = %v is outside of its range %v - %v", CtrlParamNames[e.Param], e.Val, e.Min, e.Max)
+}
+
+// Validate returns a CtrlRangeError for the first param that is outside the range from min to max, or nil if all are in range
+func (vtc *VocalTractCtrl) Validate(min, max *VocalTractCtrl) error {
+	for i := 0; i < NCtrlParams; i++ {
+		v, mn, mx := *vtc.ParamVal(i), *min.ParamVal(i), *max.ParamVal(i)
+		if !(v >= mn && v <= mx) { // also catches NaN
+			return &CtrlRangeError{Param: CtrlParamIdxs(i), Val: v, Min: mn, Max: mx}
+		}
+	}
+	return nil
+}
+
+// Clamp limits each param to the range from min to max -- a param that is not a number is set to its min
+func (vtc *VocalTractCtrl) Clamp(min, max *VocalTractCtrl) {
+	for i := 0; i < NCtrlParams; i++ {
+		v, mn, mx := vtc.ParamVal(i), *min.ParamVal(i), *max.ParamVal(i)
+		switch {
+		case *v > mx:
+			*v = mx
+		case !(*v >= mn):
+			*v = mn
+		}
+	}
+}
+
+// CheckCtrl validates the control params against the CtrlMin - CtrlMax ranges, or clamps
+// them to the ranges if ClampCtrl -- the error is nil if clamped
+func (vt *VocalTract) CheckCtrl(ctrl *VocalTractCtrl) error {
+	if vt.ClampCtrl {
+		ctrl.Clamp(&vt.CtrlMin, &vt.CtrlMax)
+		return nil
+	}
+	return ctrl.Validate(&vt.CtrlMin, &vt.CtrlMax)
+}
+
+// SetCtrl sets the current control params (the targets for the next control period), after
+// checking them with CheckCtrl -- if they are out of range, the current params are unchanged
+func (vt *VocalTract) SetCtrl(ctrl *VocalTractCtrl) error {
+	c := *ctrl
+	if err := vt.CheckCtrl(&c); err != nil {
+		return err
+	}
+	vt.CurControl = c
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// testRanges returns the default min and max control params
+func testRanges() (min, max VocalTractCtrl) {
+	min.DefaultMins()
+	max.DefaultMaxs()
+	return
+}
+
+func TestValidate(t *testing.T) {
+	min, max := testRanges()
+	nan := float32(math.NaN())
+	tests := []struct {
+		param CtrlParamIdxs
+		val   float32
+		ok    bool
+	}{
+		{GlotPitchIdx, -20, true},
+		{GlotPitchIdx, 10, true},
+		{GlotPitchIdx, 10.5, false},
+		{GlotVolIdx, -1, false},
+		{FricCfIdx, 50, false},
+		{Radius4Idx, 3.1, false},
+		{VelumIdx, 0, false},
+		{AspVolIdx, nan, false},
+	}
+	for _, tt := range tests {
+		var ctrl VocalTractCtrl
+		ctrl.Init()
+		*ctrl.ParamVal(int(tt.param)) = tt.val
+		err := ctrl.Validate(&min, &max)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s = %v: Validate error %v", CtrlParamNames[tt.param], tt.val, err)
+			}
+			continue
+		}
+		var re *CtrlRangeError
+		if !errors.As(err, &re) {
+			t.Errorf("%s = %v: Validate error = %v, want a CtrlRangeError", CtrlParamNames[tt.param], tt.val, err)
+			continue
+		}
+		if re.Param != tt.param || re.Min != *min.ParamVal(int(tt.param)) || re.Max != *max.ParamVal(int(tt.param)) {
+			t.Errorf("%s = %v: CtrlRangeError for %s with range %v - %v", CtrlParamNames[tt.param], tt.val, CtrlParamNames[re.Param], re.Min, re.Max)
+		}
+	}
+}
+
+func TestClamp(t *testing.T) {
+	min, max := testRanges()
+	var ctrl VocalTractCtrl
+	ctrl.Init()
+	ctrl.GlotPitch = 30
+	ctrl.GlotVol = -5
+	ctrl.FricCf = float32(math.NaN())
+	ctrl.Radii[2] = 2
+	ctrl.Clamp(&min, &max)
+	if ctrl.GlotPitch != max.GlotPitch || ctrl.GlotVol != min.GlotVol || ctrl.FricCf != min.FricCf || ctrl.Radii[2] != 2 {
+		t.Errorf("clamped pitch, volume, fric cf and radius = %v, %v, %v, %v, want %v, %v, %v, 2",
+			ctrl.GlotPitch, ctrl.GlotVol, ctrl.FricCf, ctrl.Radii[2], max.GlotPitch, min.GlotVol, min.FricCf)
+	}
+	if err := ctrl.Validate(&min, &max); err != nil {
+		t.Errorf("Validate after Clamp: error %v", err)
+	}
+}
+
+func TestSetCtrl(t *testing.T) {
+	vt := newTestVocalTract(t)
+	vt.CurControl.Init()
+	prv := vt.CurControl
+	bad := prv
+	bad.Radii[2] = 5
+	if err := vt.SetCtrl(&bad); err == nil || vt.CurControl != prv {
+		t.Errorf("SetCtrl out of range: error %v, want an error and the params unchanged", err)
+	}
+	var ce *CtrlCountError
+	if err := vt.CtrlFromValues([]float32{1, 2}, false); !errors.As(err, &ce) || ce.N != 2 {
+		t.Errorf("CtrlFromValues with 2 values: error %v, want a CtrlCountError for 2", err)
+	}
+	if err := vt.CtrlFromValues([]float32{1, 2}, true); !errors.As(err, &ce) {
+		t.Errorf("CtrlFromValues with 2 normalized values: error %v, want a CtrlCountError", err)
+	}
+
+	// normalized values round trip
+	want := prv
+	want.GlotVol = 30
+	want.Velum = 0.8
+	if err := vt.CtrlFromValues(want.NormValues(&vt.CtrlMin, &vt.CtrlMax), true); err != nil {
+		t.Fatalf("CtrlFromValues normalized error: %v", err)
+	}
+	for i := 0; i < NCtrlParams; i++ {
+		if d := *vt.CurControl.ParamVal(i) - *want.ParamVal(i); math.Abs(float64(d)) > 1e-3 {
+			t.Errorf("%s from normalized values = %v, want %v", CtrlParamNames[i], *vt.CurControl.ParamVal(i), *want.ParamVal(i))
+		}
+	}
+
+	vt.ClampCtrl = true
+	if err := vt.SetCtrl(&bad); err != nil || vt.CurControl.Radii[2] != vt.CtrlMax.Radii[2] {
+		t.Errorf("SetCtrl out of range with ClampCtrl: error %v and radius %v, want nil and %v", err, vt.CurControl.Radii[2], vt.CtrlMax.Radii[2])
+	}
+	vt.ClampCtrl = false
+
+	// Synthesize checks the current params
+	vt.CurControl.GlotVol = 70
+	n := len(vt.OutputData)
+	if err := vt.Synthesize(false); err == nil || len(vt.OutputData) != n {
+		t.Errorf("Synthesize out of range: error %v with %d samples synthesized, want an error and none", err, len(vt.OutputData)-n)
+	}
+}
+
+func TestErrTractLength(t *testing.T) {
+	vt := newTestVocalTract(t)
+	vt.Voice.TractLength = 0
+	if err := vt.SynthReset(true); !errors.Is(err, ErrTractLength) {
+		t.Errorf("SynthReset with zero TractLength: error %v, want ErrTractLength", err)
+	}
+	if _, _, err := vt.Analyze(); !errors.Is(err, ErrTractLength) {
+		t.Errorf("Analyze with zero TractLength: error %v, want ErrTractLength", err)
+	}
+}
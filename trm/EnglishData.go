@@ -0,0 +1,177 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+// The English tables built into the package are a small STARTER SET, written for this package,
+// that is enough to synthesize simple English words -- they are NOT the gnuspeech English
+// posture and dictionary data, which remain to be ported.  For better coverage, load complete
+// tables from files with OpenPhones and OpenDict.  Words that are not in the dictionary are
+// converted to phones by LetterToSound (see WordPhones).
+
+// EnglishPhones is the English phone table that is built into the package, used by
+// LoadEnglishPhones -- it holds approximate articulatory targets for the basic English vowels
+// (with stressed variants marked with ') and consonants.
+// Format is tab-separated with emergent-style headers: phone, cats (space-separated categories
+// used for matching Rules), duration (ms), transition (ms), and phone_data, which are the
+// NCtrlParams VocalTractCtrl values in CtrlParamIdxs order.
+const EnglishPhones = `_H:	$phone	$cats	%duration	%transition	%phone_data[1:0]<1:15>	%phone_data[1:1]	%phone_data[1:2]	%phone_data[1:3]	%phone_data[1:4]	%phone_data[1:5]	%phone_data[1:6]	%phone_data[1:7]	%phone_data[1:8]	%phone_data[1:9]	%phone_data[1:10]	%phone_data[1:11]	%phone_data[1:12]	%phone_data[1:13]	%phone_data[1:14]
+_D:	#	silence	80	20	0	0	0	0	4	2500	2000	0.8	0.9	1	1.1	1.2	1	1	0.1
+_D:	ee	vowel voiced	110	30	0	60	0	0	4	2500	2000	1	1.6	1.9	1.2	0.35	0.3	1	0.1
+_D:	ee'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	1	1.6	1.9	1.2	0.35	0.3	1	0.1
+_D:	i	vowel voiced	110	30	0	60	0	0	4	2500	2000	1	1.4	1.6	1.2	0.6	0.5	1	0.1
+_D:	i'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	1	1.4	1.6	1.2	0.6	0.5	1	0.1
+_D:	e	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.9	1.1	1.3	1.3	0.9	0.7	1.1	0.1
+_D:	e'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.9	1.1	1.3	1.3	0.9	0.7	1.1	0.1
+_D:	a	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.7	0.8	1	1.5	1.4	1.1	1.4	0.1
+_D:	a'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.7	0.8	1	1.5	1.4	1.1	1.4	0.1
+_D:	aa	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.5	0.5	0.7	1.4	1.8	1.6	1.5	0.1
+_D:	aa'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.5	0.5	0.7	1.4	1.8	1.6	1.5	0.1
+_D:	o	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.5	0.5	0.8	1.2	1.5	1.2	1	0.1
+_D:	o'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.5	0.5	0.8	1.2	1.5	1.2	1	0.1
+_D:	aw	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.6	0.5	0.9	1	1.3	1	0.6	0.1
+_D:	aw'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.6	0.5	0.9	1	1.3	1	0.6	0.1
+_D:	u	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.9	0.9	1.1	0.8	1	0.9	0.5	0.1
+_D:	u'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.9	0.9	1.1	0.8	1	0.9	0.5	0.1
+_D:	uu	vowel voiced	110	30	0	60	0	0	4	2500	2000	1.1	1.2	1.3	0.5	0.9	0.9	0.3	0.1
+_D:	uu'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	1.1	1.2	1.3	0.5	0.9	0.9	0.3	0.1
+_D:	uh	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.8	0.9	1	1.1	1.2	1	1	0.1
+_D:	uh'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.8	0.9	1	1.1	1.2	1	1	0.1
+_D:	er	vowel voiced	110	30	0	60	0	0	4	2500	2000	0.9	1	1	0.9	0.8	0.8	0.8	0.1
+_D:	er'	vowel voiced stressed	150	30	2	60	0	0	4	2500	2000	0.9	1	1	0.9	0.8	0.8	0.8	0.1
+_D:	p	consonant stop unvoiced	70	20	0	0	0	10	7	1000	4000	0.8	0.9	1	1.1	1.2	1	0	0.1
+_D:	b	consonant stop voiced	60	20	0	43	0	0	4	2500	2000	0.8	0.9	1	1.1	1.2	1	0	0.1
+_D:	t	consonant stop unvoiced	70	20	0	0	0	10	6	4000	3000	0.8	0.9	1	1.1	1	0	1	0.1
+_D:	d	consonant stop voiced	60	20	0	43	0	0	4	2500	2000	0.8	0.9	1	1.1	1	0	1	0.1
+_D:	k	consonant stop unvoiced	70	20	0	0	0	10	4	2000	2000	0.8	0.9	1	1	0	1	1	0.1
+_D:	g	consonant stop voiced	60	20	0	43	0	0	4	2500	2000	0.8	0.9	1	1	0	1	1	0.1
+_D:	m	consonant nasal voiced	70	25	0	60	0	0	4	2500	2000	0.8	0.9	1	1.1	1.2	1	0	1.5
+_D:	n	consonant nasal voiced	70	25	0	60	0	0	4	2500	2000	0.8	0.9	1	1.1	1	0	1	1.5
+_D:	ng	consonant nasal voiced	70	25	0	60	0	0	4	2500	2000	0.8	0.9	1	1	0	1	1	1.5
+_D:	f	consonant fricative unvoiced	90	20	0	0	0	24	7	5000	4000	0.8	0.9	1	1.1	1.2	1	0.2	0.1
+_D:	v	consonant fricative voiced	70	20	0	54	0	20	7	5000	4000	0.8	0.9	1	1.1	1.2	1	0.2	0.1
+_D:	th	consonant fricative unvoiced	90	20	0	0	0	22	6.5	5000	4000	0.8	0.9	1	1.1	1	0.2	1	0.1
+_D:	dh	consonant fricative voiced	60	20	0	54	0	18	6.5	5000	4000	0.8	0.9	1	1.1	1	0.2	1	0.1
+_D:	s	consonant fricative unvoiced	100	20	0	0	0	24	6	5500	1500	0.8	0.9	1	1.1	1	0.15	0.8	0.1
+_D:	z	consonant fricative voiced	80	20	0	54	0	22	6	5500	1500	0.8	0.9	1	1.1	1	0.15	0.8	0.1
+_D:	sh	consonant fricative unvoiced	100	20	0	0	0	24	5	2500	2000	0.8	0.9	1	1.1	0.2	0.9	0.7	0.1
+_D:	zh	consonant fricative voiced	80	20	0	54	0	22	5	2500	2000	0.8	0.9	1	1.1	0.2	0.9	0.7	0.1
+_D:	ch	consonant affricate unvoiced	90	20	0	0	0	24	5	2800	2000	0.8	0.9	1	1.1	0.15	0.6	0.7	0.1
+_D:	j	consonant affricate voiced	80	20	0	50	0	20	5	2800	2000	0.8	0.9	1	1.1	0.15	0.6	0.7	0.1
+_D:	h	consonant aspirate unvoiced	70	20	0	0	10	0	4	2500	2000	0.8	0.9	1	1.1	1.2	1	1	0.1
+_D:	l	consonant approximant voiced	70	30	0	60	0	0	4	2500	2000	0.8	0.9	1	1.1	1	0.3	1	0.1
+_D:	r	consonant approximant voiced	70	30	0	60	0	0	4	2500	2000	0.8	0.7	1	1	0.8	0.9	0.6	0.1
+_D:	w	consonant approximant voiced	60	30	0	60	0	0	4	2500	2000	0.9	0.9	1.1	0.6	1	1	0.2	0.1
+_D:	y	consonant approximant voiced	60	30	0	60	0	0	4	2500	2000	1	1.6	1.9	1.2	0.2	0.3	1	0.1
+`
+
+// EnglishDict is the English dictionary that is built into the package, used by LoadEnglishDict --
+// it covers about 100 common words, spelled with the phones of EnglishPhones: phones are separated
+// by _, syllables by . and ' marks a stressed vowel.
+const EnglishDict = `_H:	$word	$phones
+_D:	a	uh
+_D:	about	uh.b_'a_u_t
+_D:	all	'aw_l
+_D:	an	a_n
+_D:	and	a_n_d
+_D:	are	'aa
+_D:	as	a_z
+_D:	at	a_t
+_D:	be	b_'ee
+_D:	been	b_'ee_n
+_D:	but	b_'uh_t
+_D:	by	b_'aa_i
+_D:	call	k_'aw_l
+_D:	can	k_'a_n
+_D:	come	k_'uh_m
+_D:	could	k_'u_d
+_D:	day	d_'e_i
+_D:	did	d_'i_d
+_D:	do	d_'uu
+_D:	down	d_'a_u_n
+_D:	each	'ee_ch
+_D:	find	f_'aa_i_n_d
+_D:	first	f_'er_s_t
+_D:	for	f_'aw
+_D:	from	f_r_'o_m
+_D:	go	g_'er_u
+_D:	had	h_'a_d
+_D:	has	h_'a_z
+_D:	have	h_'a_v
+_D:	he	h_'ee
+_D:	hello	h_e.l_'er_u
+_D:	her	h_'er
+_D:	him	h_'i_m
+_D:	his	h_'i_z
+_D:	hot	h_'o_t
+_D:	how	h_'a_u
+_D:	i	'aa_i
+_D:	if	'i_f
+_D:	in	'i_n
+_D:	is	'i_z
+_D:	it	'i_t
+_D:	know	n_'er_u
+_D:	like	l_'aa_i_k
+_D:	long	l_'o_ng
+_D:	look	l_'u_k
+_D:	make	m_'e_i_k
+_D:	many	m_'e.n_ee
+_D:	may	m_'e_i
+_D:	me	m_'ee
+_D:	more	m_'aw
+_D:	most	m_'er_u_s_t
+_D:	my	m_'aa_i
+_D:	no	n_'er_u
+_D:	now	n_'a_u
+_D:	number	n_'uh_m.b_er
+_D:	of	uh_v
+_D:	on	'o_n
+_D:	one	w_'uh_n
+_D:	or	'aw
+_D:	other	'uh.dh_er
+_D:	out	'a_u_t
+_D:	over	'er_u.v_er
+_D:	people	p_'ee.p_uh_l
+_D:	said	s_'e_d
+_D:	see	s_'ee
+_D:	she	sh_'ee
+_D:	side	s_'aa_i_d
+_D:	so	s_'er_u
+_D:	some	s_'uh_m
+_D:	sound	s_'a_u_n_d
+_D:	speech	s_p_'ee_ch
+_D:	than	dh_'a_n
+_D:	that	dh_'a_t
+_D:	the	dh_uh
+_D:	their	dh_'e_uh
+_D:	them	dh_'e_m
+_D:	then	dh_'e_n
+_D:	there	dh_'e_uh
+_D:	these	dh_'ee_z
+_D:	they	dh_'e_i
+_D:	thing	th_'i_ng
+_D:	this	dh_'i_s
+_D:	time	t_'aa_i_m
+_D:	to	t_uu
+_D:	two	t_'uu
+_D:	up	'uh_p
+_D:	use	y_'uu_z
+_D:	was	w_'o_z
+_D:	water	w_'aw.t_er
+_D:	way	w_'e_i
+_D:	we	w_'ee
+_D:	were	w_'er
+_D:	what	w_'o_t
+_D:	when	w_'e_n
+_D:	which	w_'i_ch
+_D:	who	h_'uu
+_D:	will	w_'i_l
+_D:	with	w_'i_dh
+_D:	word	w_'er_d
+_D:	would	w_'u_d
+_D:	write	r_'aa_i_t
+_D:	yes	y_'e_s
+_D:	you	y_'uu
+_D:	your	y_'aw
+`
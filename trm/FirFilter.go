@@ -28,6 +28,8 @@
 package trm
 
 import (
+	"errors"
+
 	"github.com/chewxy/math32"
 )
 
@@ -40,19 +42,30 @@ type FirFilter struct {
 	Coef  []float32
 }
 
-func (ff *FirFilter) Init(beta, gamma, cutoff float32) {
+// Init computes the coefficients of a maximally flat lowpass filter, with beta the center frequency
+// of the transition band and gamma its width (as fractions of the sampling frequency), trims
+// coefficients below cutoff, and allocates the taps of the filter
+func (ff *FirFilter) Init(beta, gamma, cutoff float32) error {
 	coefficients := make([]float32, Limit+1)
 
 	var nCoefficients int
 
 	// determine ideal low pass filter coefficients
-	ff.MaximallyFlat(beta, gamma, &nCoefficients, coefficients)
+	if err := ff.MaximallyFlat(beta, gamma, &nCoefficients, coefficients); err != nil {
+		return err
+	}
 
 	// trim low-value coefficients
 	ff.Trim(cutoff, &nCoefficients, coefficients)
 
 	// determine the number of taps in the filter
 	nTaps := (nCoefficients * 2) - 1
+	if nTaps < 1 {
+		return errors.New("trm.FirFilter.Init: no coefficients above cutoff")
+	}
+	ff.NTaps = nTaps
+	ff.Data = make([]float32, nTaps)
+	ff.Coef = make([]float32, nTaps)
 
 	// initialize the coefficients
 	increment := -1
@@ -66,6 +79,7 @@ func (ff *FirFilter) Init(beta, gamma, cutoff float32) {
 		}
 	}
 	ff.Ptr = 0
+	return nil
 }
 
 //Reset resets the data and sets the pointer to first element
@@ -78,8 +92,9 @@ func (ff *FirFilter) Reset() {
 
 // MaximallyFlat Calculates coefficients for a linear phase lowpass FIR
 // filter, with beta being the center frequency of the transition band (as a fraction
-// of the sampling frequency), and gamme the width of the transition band
-func (ff *FirFilter) MaximallyFlat(beta, gamma float32, np *int, coefficients []float32) int {
+// of the sampling frequency), and gamme the width of the transition band --
+// the np coefficients are stored in coefficients[1:np+1]
+func (ff *FirFilter) MaximallyFlat(beta, gamma float32, np *int, coefficients []float32) error {
 	a := make([]float32, Limit+1)
 	c := make([]float32, Limit+1)
 
@@ -88,8 +103,7 @@ func (ff *FirFilter) MaximallyFlat(beta, gamma float32, np *int, coefficients []
 
 	// cut-off frequency must be between 0 hz and nyquist
 	if beta <= 0.0 || beta >= 0.5 {
-		return 0
-		// THROW_EXCEPTION(TRMException, "Beta out of range.");
+		return errors.New("trm.MaximallyFlat: beta out of range")
 	}
 
 	// transition band must fit with the stop band
@@ -100,19 +114,17 @@ func (ff *FirFilter) MaximallyFlat(beta, gamma float32, np *int, coefficients []
 		betaMin = 1.0 - 2.0*beta
 	}
 	if gamma <= 0.0 || gamma >= betaMin {
-		return 0
-		// THROW_EXCEPTION(TRMException, "Gamma out of range.");
+		return errors.New("trm.MaximallyFlat: gamma out of range")
 	}
 
 	// make sure transition band not too small
 	nt := int(1.0 / (4.0 * gamma * gamma))
 	if nt > 160 {
-		return 0
-		// THROW_EXCEPTION(TRMException, "Gamma too small.");
+		return errors.New("trm.MaximallyFlat: gamma too small")
 	}
 
 	// calculate the rational approximation to the cut-off point
-	ac := 1.0 + math32.Cos((2.0*math32.Pi)*beta)/2.0
+	ac := (1.0 + math32.Cos((2.0*math32.Pi)*beta)) / 2.0
 	var numerator int
 	RationalApproximation(ac, &nt, &numerator, np)
 
@@ -152,23 +164,23 @@ func (ff *FirFilter) MaximallyFlat(beta, gamma float32, np *int, coefficients []
 
 	// Calculate weighting coefficients by an n-point idft
 	for i := 1; i <= *np; i++ {
-		ff.Coef[i] = a[1] / 2.0
+		coefficients[i] = a[1] / 2.0
 		for j := 2; j <= *np; j++ {
 			m := ((i - 1) * (j - 1)) % n
 			if m > nt {
 				m = n - m
 			}
-			ff.Coef[i] += c[m+1] * a[j]
+			coefficients[i] += c[m+1] * a[j]
 		}
-		ff.Coef[i] *= 2.0 / float32(n)
+		coefficients[i] *= 2.0 / float32(n)
 	}
-	return 0
+	return nil
 }
 
 // Trims the higher order coefficients of the FIR filter which fall below the cutoff value
 func (ff *FirFilter) Trim(cutoff float32, nCoefficients *int, coefficients []float32) {
 	for i := *nCoefficients; i > 0; i-- {
-		if math32.Abs(ff.Coef[i]) >= math32.Abs(cutoff) {
+		if math32.Abs(coefficients[i]) >= math32.Abs(cutoff) {
 			*nCoefficients = i
 			return
 		}
@@ -0,0 +1,148 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"github.com/chewxy/math32"
+)
+
+// The parametric glottal flow models fill a wavetable with one period of the glottal
+// flow, normalized to a peak of 1, with the open phase starting at the beginning of the table,
+// in the same form as the Pulse table, so they are played by the same oscillator.
+
+// LFWave fills the table with one period of the Liljencrants-Fant (LF) glottal flow, with the shape
+// of the flow derivative given by the single Rd parameter (Fant, 1995), ranging from about 0.3
+// (tense, pressed voice) through 1 (modal) to 2.7 (lax, breathy voice)
+func LFWave(rd float32, table []float32) {
+	if rd < 0.3 {
+		rd = 0.3
+	} else if rd > 2.7 {
+		rd = 2.7
+	}
+	// Rd to the R parameters (times relative to a period of 1)
+	rap := (-1 + 4.8*rd) / 100
+	rkp := (22.4 + 11.8*rd) / 100
+	rgp := rkp / (4 * ((0.11 * rd / (0.5 + 1.2*rkp)) - rap))
+	tp := 1 / (2 * rgp)
+	te := tp * (1 + rkp)
+	ta := rap
+	if te > 0.99 {
+		te = 0.99
+	}
+	wg := math32.Pi / tp
+
+	// epsilon of the exponential return phase: eps * ta = 1 - exp(-eps * (1 - te))
+	eps := 1 / ta
+	for i := 0; i < 50; i++ {
+		eps = (1 - math32.Exp(-eps*(1-te))) / ta
+	}
+
+	// oversampled derivative, as a function of the growth factor alpha of the open phase
+	n := 16 * len(table)
+	dt := 1 / float32(n)
+	deriv := make([]float32, n)
+	fill := func(alpha float32) float32 {
+		e0 := -1 / (math32.Exp(alpha*te) * math32.Sin(wg*te))
+		sum := float32(0)
+		for i := range deriv {
+			t := (float32(i) + 0.5) * dt
+			if t <= te {
+				deriv[i] = e0 * math32.Exp(alpha*t) * math32.Sin(wg*t)
+			} else {
+				deriv[i] = -(math32.Exp(-eps*(t-te)) - math32.Exp(-eps*(1-te))) / (eps * ta)
+			}
+			sum += deriv[i]
+		}
+		return sum * dt
+	}
+
+	// alpha is found by bisection so that the net flow over the period is zero --
+	// the net flow decreases with alpha
+	lo, hi := float32(-50), float32(100)
+	for i := 0; i < 60; i++ {
+		mid := 0.5 * (lo + hi)
+		if fill(mid) > 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	fill(0.5 * (lo + hi))
+
+	// integrate to the flow, sampled into the table
+	flow := float32(0)
+	sub := n / len(table)
+	for i := range table {
+		for j := 0; j < sub; j++ {
+			flow += deriv[i*sub+j] * dt
+		}
+		table[i] = flow
+	}
+	normWave(table)
+}
+
+// RosenbergWave fills the table with one period of the Rosenberg (trigonometric) glottal flow,
+// open for the fraction oq of the period, with the fraction rise of the open phase rising and the rest falling
+func RosenbergWave(oq, rise float32, table []float32) {
+	n := float32(len(table))
+	tp := oq * rise * n
+	tn := oq * (1 - rise) * n
+	for i := range table {
+		t := float32(i)
+		switch {
+		case t < tp:
+			table[i] = 0.5 * (1 - math32.Cos(math32.Pi*t/tp))
+		case t < tp+tn:
+			table[i] = math32.Cos(0.5 * math32.Pi * (t - tp) / tn)
+		default:
+			table[i] = 0
+		}
+	}
+}
+
+// KLGLOTT88Wave fills the table with one period of the KLGLOTT88 (Klatt & Klatt, 1990) glottal flow,
+// a polynomial pulse of the form t^2 - t^3, open for the fraction oq of the period
+func KLGLOTT88Wave(oq float32, table []float32) {
+	n := oq * float32(len(table))
+	for i := range table {
+		x := float32(i) / n
+		if x < 1 {
+			table[i] = 27.0 / 4.0 * (x*x - x*x*x)
+		} else {
+			table[i] = 0
+		}
+	}
+}
+
+// normWave normalizes the table to the range 0-1
+func normWave(table []float32) {
+	mn, mx := table[0], table[0]
+	for _, v := range table {
+		mn = math32.Min(mn, v)
+		mx = math32.Max(mx, v)
+	}
+	if mx <= mn {
+		return
+	}
+	for i, v := range table {
+		table[i] = (v - mn) / (mx - mn)
+	}
+}
+
+// TiltCoef returns the coefficient of the one-pole lowpass filter that attenuates the glottal
+// source by tilt dB at 3 kHz, at the given sample rate, with unity gain at 0 Hz
+func TiltCoef(tilt, sampleRate float32) float32 {
+	if tilt <= 0 {
+		return 0
+	}
+	g2 := math32.Pow(10, -tilt/10) // squared gain at 3 kHz
+	c := math32.Cos(2 * math32.Pi * 3000 / sampleRate)
+	b := 1 - g2*c
+	d := b*b - (1-g2)*(1-g2)
+	if d < 0 {
+		d = 0
+	}
+	return (b - math32.Sqrt(d)) / (1 - g2)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// peakIdx returns the index of the max value of the table
+func peakIdx(table []float32) int {
+	pk := 0
+	for i, v := range table {
+		if v > table[pk] {
+			pk = i
+		}
+	}
+	return pk
+}
+
+func TestLFWave(t *testing.T) {
+	tests := []struct {
+		rd   float32
+		peak float32 // time of the peak flow (tp) as a fraction of the period
+	}{
+		{0.3, 0.28},
+		{1, 0.484},
+		{2.7, 0.51},
+	}
+	for _, tt := range tests {
+		table := make([]float32, TableLength)
+		LFWave(tt.rd, table)
+		for i, v := range table {
+			if v < 0 || v > 1 || v != v {
+				t.Fatalf("Rd %v: value %d = %v, outside of 0-1", tt.rd, i, v)
+			}
+		}
+		pk := peakIdx(table)
+		if table[pk] != 1 {
+			t.Errorf("Rd %v: peak = %v, want 1", tt.rd, table[pk])
+		}
+		if tp := float32(pk) / TableLength; math.Abs(float64(tp-tt.peak)) > 0.02 {
+			t.Errorf("Rd %v: peak at %v of the period, want %v", tt.rd, tp, tt.peak)
+		}
+		// zero net flow derivative: the flow ends where it starts
+		if d := table[TableLength-1] - table[0]; math.Abs(float64(d)) > 0.02 {
+			t.Errorf("Rd %v: flow at the end of the period is %v from the start", tt.rd, d)
+		}
+	}
+	// Rd is limited to the range 0.3 - 2.7
+	lo := make([]float32, TableLength)
+	lim := make([]float32, TableLength)
+	LFWave(0.1, lo)
+	LFWave(0.3, lim)
+	for i := range lo {
+		if lo[i] != lim[i] {
+			t.Fatalf("Rd 0.1 value %d = %v, want %v as for Rd 0.3", i, lo[i], lim[i])
+		}
+	}
+}
+
+func TestRosenbergWave(t *testing.T) {
+	table := make([]float32, 100)
+	RosenbergWave(0.6, 0.6, table) // rises for 36 and falls for 24 samples
+	if pk := peakIdx(table); pk != 36 || table[pk] != 1 {
+		t.Errorf("peak %v at %d, want 1 at 36", table[pk], pk)
+	}
+	if table[0] != 0 || math.Abs(float64(table[18]-0.5)) > 1e-6 {
+		t.Errorf("rise starts at %v and is %v half way, want 0 and 0.5", table[0], table[18])
+	}
+	for i := 60; i < 100; i++ {
+		if table[i] != 0 {
+			t.Errorf("value %d in the closed phase = %v, want 0", i, table[i])
+		}
+	}
+}
+
+func TestKLGLOTT88Wave(t *testing.T) {
+	table := make([]float32, 300)
+	KLGLOTT88Wave(0.5, table) // open for 150 samples, with the peak at 2/3 of the open phase
+	if pk := peakIdx(table); pk != 100 || math.Abs(float64(table[pk]-1)) > 1e-5 {
+		t.Errorf("peak %v at %d, want 1 at 100", table[pk], pk)
+	}
+	for i := 150; i < 300; i++ {
+		if table[i] != 0 {
+			t.Errorf("value %d in the closed phase = %v, want 0", i, table[i])
+		}
+	}
+}
+
+func TestTiltCoef(t *testing.T) {
+	if c := TiltCoef(0, 20000); c != 0 {
+		t.Errorf("TiltCoef(0) = %v, want 0", c)
+	}
+	for _, tilt := range []float32{3, 6, 12} {
+		for _, sr := range []float32{20000, 44100} {
+			c := float64(TiltCoef(tilt, sr))
+			// gain of y = (1 - c) x + c y[n-1] at 3 kHz
+			z := cmplx.Exp(complex(0, -2*math.Pi*3000/float64(sr)))
+			g := cmplx.Abs(complex(1-c, 0) / (1 - complex(c, 0)*z))
+			if db := -20 * math.Log10(g); math.Abs(db-float64(tilt)) > 0.01 {
+				t.Errorf("TiltCoef(%v, %v) = %v, attenuating 3 kHz by %v dB", tilt, sr, c, db)
+			}
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"strings"
+	"sync"
+)
+
+// EnglishLTSRules are the letter-to-sound rules for English, from the Naval Research Laboratory
+// rules of Elovitz, Johnson, McHugh & Shore (1976), used for words that are not in the dictionary.
+// Each rule is written as left[match]right=phones: the letters of match are converted to the phones
+// when preceded by the left context and followed by the right context. Rules are tried in order
+// for the letter at the current position, so more specific rules go first. In the contexts,
+// letters match themselves, a space matches the start or end of the word, # one or more vowels
+// (A E I O U Y), : zero or more consonants, ^ one consonant, . one voiced consonant (B D G J L M N R V W Z),
+// + a front vowel (E I Y), % a suffix (ER E ES ED ING ELY -- right context only), & a sibilant
+// (S C G Z X J CH SH), and @ one of T S R D L Z N J TH CH SH, after which a long u is pronounced oo.
+// The phones are ARPAbet, and are converted to the phones of EnglishPhones by ARPAbetPhones.
+var EnglishLTSRules = []string{
+	// A
+	"[A] =AX", " [ARE] =AAR", " [AR]O=AXR", "[AR]#=EHR", " ^[AS]#=EYS", "[A]WA=AX", "[AW]=AO",
+	" :[ANY]=EHNIY", "[A]^+#=EY", "#:[ALLY]=AXLIY", " [AL]#=AXL", "[AGAIN]=AXGEHN", "#:[AG]E=IHJ",
+	"[A]^+:#=AE", " :[A]^+ =EY", "[A]^%=EY", " [ARR]=AXR", "[ARR]=AER", " :[AR] =AAR", "[AR] =ER",
+	"[AR]=AAR", "[AIR]=EHR", "[AI]=EY", "[AY]=EY", "[AU]=AO", "#:[AL] =AXL", "#:[ALS] =AXLZ",
+	"[ALK]=AOK", "[AL]^=AOL", " :[ABLE]=EYBAXL", "[ABLE]=AXBAXL", "[ANG]+=EYNJ", "[A]=AE",
+	// B
+	" [BE]^#=BIH", "[BEING]=BIYIHNX", " [BOTH] =BOWTH", " [BUS]#=BIHZ", "[BUIL]=BIHL", "[B]=B",
+	// C
+	" [CH]^=K", "^E[CH]=K", "[CH]=CH", " S[CI]#=SAY", "[CI]A=SH", "[CI]O=SH", "[CI]EN=SH",
+	"[C]+=S", "[CK]=K", "[COM]%=KAHM", "[C]=K",
+	// D
+	"#:[DED] =DIHD", ".E[D] =D", "#^:E[D] =T", " [DE]^#=DIH", " [DO] =DUW", " [DOES]=DAHZ",
+	" [DOING]=DUWIHNX", " [DOW]=DAW", "[DU]A=JUW", "[D]=D",
+	// E
+	"#:[E] =", " ^:[E] =", " :[E] =IY", "#[ED] =D", "#:[E]D =", "[EV]ER=EHV", "[E]^%=IY",
+	"[ERI]#=IYRIY", "[ERI]=EHRIH", "#:[ER]#=ER", "[ER]#=EHR", "[ER]=ER", " [EVEN]=IYVEHN",
+	"#:[E]W=", "@[EW]=UW", "[EW]=YUW", "[E]O=IY", "#:&[ES] =IHZ", "#:[E]S =", "#:[ELY] =LIY",
+	"#:[EMENT]=MEHNT", "[EFUL]=FUHL", "[EE]=IY", "[EARN]=ERN", " [EAR]^=ER", "[EAD]=EHD",
+	"#:[EA] =IYAX", "[EA]SU=EH", "[EA]=IY", "[EIGH]=EY", "[EI]=IY", " [EYE]=AY", "[EY]=IY",
+	"[EU]=YUW", "[E]=EH",
+	// F
+	"[FUL]=FUHL", "[F]=F",
+	// G
+	"[GIV]=GIHV", " [G]I^=G", "[GE]T=GEH", "SU[GGES]=GJEHS", "[GG]=G", " B#[G]=G", "[G]+=J",
+	"[GREAT]=GREYT", "#[GH]=", "[G]=G",
+	// H
+	" [HAV]=HHAEV", " [HERE]=HHIYR", " [HOUR]=AWER", "[HOW]=HHAW", "[H]#=HH", "[H]=",
+	// I
+	" [IN]=IHN", " [I] =AY", "[IN]D=AYN", "[IER]=IYER", "#:R[IED] =IYD", "[IED] =AYD",
+	"[IEN]=IYEHN", "[IE]T=AYEH", " :[I]%=AY", "[I]%=IY", "[IE]=IY", "[I]^+:#=IH", "[IR]#=AYR",
+	"[IZ]%=AYZ", "[IS]%=AYZ", "[I]D%=AY", "+^[I]^+=IH", "[I]T%=AY", "#^:[I]^+=IH", "[I]^+=AY",
+	"[IR]=ER", "[IGH]=AY", "[ILD]=AYLD", "[IGN] =AYN", "[IGN]^=AYN", "[IGN]%=AYN", "[IQUE]=IYK",
+	"[I]=IH",
+	// J
+	"[J]=J",
+	// K
+	" [K]N=", "[K]=K",
+	// L
+	"[LO]C#=LOW", "L[L]=", "#^:[L]%=AXL", "[LEAD]=LIYD", "[L]=L",
+	// M
+	"[MOV]=MUWV", "[M]=M",
+	// N
+	"E[NG]+=NJ", "[NG]R=NXG", "[NG]#=NXG", "[NGL]%=NXGAXL", "[NG]=NX", "[NK]=NXK",
+	" [NOW] =NAW", "[N]=N",
+	// O
+	"[OF] =AXV", "[OROUGH]=EROW", "#:[OR] =ER", "#:[ORS] =ERZ", "[OR]=AOR", " [ONE]=WAHN",
+	"[OW]=OW", " [OVER]=OWVER", "[OV]=AHV", "[O]^%=OW", "[O]^EN=OW", "[O]^I#=OW", "[OL]D=OWL",
+	"[OUGHT]=AOT", "[OUGH]=AHF", " [OU]=AW", "H[OU]S#=AW", "[OUS]=AXS", "[OUR]=AOR",
+	"[OULD]=UHD", "^[OU]^L=AH", "[OUP]=UWP", "[OU]=AW", "[OY]=OY", "[OING]=OWIHNX", "[OI]=OY",
+	"[OOR]=AOR", "[OOK]=UHK", "[OOD]=UHD", "[OO]=UW", "[O]E=OW", "[O] =OW", "[OA]=OW",
+	" [ONLY]=OWNLIY", " [ONCE]=WAHNS", "C[O]N=AA", "[O]NG=AO", " :^[O]N=AH", "I[ON]=AXN",
+	"#:[ON] =AXN", "#^[ON]=AXN", "[O]ST =OW", "[OF]^=AOF", "[OTHER]=AHDHER", "[OSS] =AOS",
+	"#:^[OM]=AHM", "[O]=AA",
+	// P
+	"[PH]=F", "[PEOP]=PIYP", "[POW]=PAW", "[PUT] =PUHT", "[P]=P",
+	// Q
+	"[QUAR]=KWAOR", "[QU]=KW", "[Q]=K",
+	// R
+	" [RE]^#=RIY", "[R]=R",
+	// S
+	"[SH]=SH", "#[SION]=ZHAXN", "[SOME]=SAHM", "#[SUR]#=ZHER", "[SUR]#=SHER", "#[SU]#=ZHUW",
+	"#[SSU]#=SHUW", "#[SED] =ZD", "#[S]#=Z", "[SAID]=SEHD", "^[SION]=SHAXN", "[S]S=",
+	".[S] =Z", "#:.E[S] =Z", "#^:##[S] =Z", "#^:#[S] =S", "U[S] =S", " :#[S] =Z", " [SCH]=SK",
+	"[S]C+=", "#[SM]=ZM", "[S]=S",
+	// T
+	" [THE] =DHAX", "[TO] =TUW", "[THAT] =DHAET", " [THIS] =DHIHS", " [THEY]=DHEY",
+	" [THERE]=DHEHR", "[THER]=DHER", "[THEIR]=DHEHR", " [THAN] =DHAEN", " [THEM] =DHEHM",
+	"[THESE] =DHIYZ", " [THEN]=DHEHN", "[THROUGH]=THRUW", "[THOSE]=DHOWZ", "[THOUGH] =DHOW",
+	" [THUS]=DHAHS", "[TH]=TH", "#:[TED] =TIHD", "S[TI]#N=CH", "[TI]O=SH", "[TI]A=SH",
+	"[TIEN]=SHAXN", "[TUR]#=CHER", "[TU]A=CHUW", " [TWO]=TUW", "[T]=T",
+	// U
+	" [UN]I=YUWN", " [UN]=AHN", " [UPON]=AXPAON", "@[UR]#=UHR", "[UR]#=YUHR", "[UR]^=ER",
+	"[U]^ =AH", "[U]^^=AH", "[UY]=AY", " G[U]#=", "G[U]%=", "G[U]#=W", "#N[U]=YUW", "@[U]=UW",
+	"[U]=YUW",
+	// V
+	"[VIEW]=VYUW", "[V]=V",
+	// W
+	" [WERE]=WER", "[WA]S=WAA", "[WA]T=WAA", "[WHERE]=WHEHR", "[WHAT]=WHAAT", "[WHOL]=HHOWL",
+	"[WHO]=HHUW", "[WH]=WH", "[WAR]=WAOR", "[WOR]^=WER", "[WR]=R", "[W]=W",
+	// X
+	"[X]=KS",
+	// Y
+	"[YOUNG]=YAHNX", " [YOU]=YUW", " [YES]=YEHS", " [Y]=Y", "#^:[Y] =IY", "#^:[Y]I=IY",
+	" :[Y] =AY", " :[Y]#=AY", " :[Y]^+:#=IH", " :[Y]^#=AY", "[Y]=IH",
+	// Z
+	"[Z]=Z",
+}
+
+// ARPAbetPhones maps the ARPAbet phones of the EnglishLTSRules to the phones of EnglishPhones --
+// diphthongs are a sequence of two phones
+var ARPAbetPhones = map[string]string{
+	"IY": "ee", "IH": "i", "EY": "e_i", "EH": "e", "AE": "a", "AA": "aa", "AO": "aw", "OW": "er_u",
+	"UH": "u", "UW": "uu", "ER": "er", "AX": "uh", "AH": "uh", "AY": "aa_i", "AW": "a_u", "OY": "aw_i",
+	"P": "p", "B": "b", "T": "t", "D": "d", "K": "k", "G": "g", "F": "f", "V": "v", "TH": "th",
+	"DH": "dh", "S": "s", "Z": "z", "SH": "sh", "ZH": "zh", "HH": "h", "M": "m", "N": "n",
+	"NX": "ng", "L": "l", "W": "w", "WH": "w", "Y": "y", "R": "r", "CH": "ch", "J": "j",
+}
+
+// arpaVowels are the ARPAbet vowels, which are the nuclei of syllables
+var arpaVowels = map[string]bool{
+	"IY": true, "IH": true, "EY": true, "EH": true, "AE": true, "AA": true, "AO": true, "OW": true,
+	"UH": true, "UW": true, "ER": true, "AX": true, "AH": true, "AY": true, "AW": true, "OY": true,
+}
+
+// arpaOnsets are the two-consonant clusters that can begin a syllable
+var arpaOnsets = map[string]bool{
+	"S T": true, "S P": true, "S K": true, "S M": true, "S N": true, "S L": true, "S W": true,
+	"P L": true, "P R": true, "B L": true, "B R": true, "T R": true, "D R": true, "K L": true,
+	"K R": true, "K W": true, "G L": true, "G R": true, "F L": true, "F R": true, "TH R": true,
+	"SH R": true,
+}
+
+// ltsRule is a parsed letter-to-sound rule
+type ltsRule struct {
+	left, match, right string
+	phones             []string
+}
+
+var ltsRules map[byte][]ltsRule
+var ltsOnce sync.Once
+
+// parseLTSRules parses the EnglishLTSRules into ltsRules, by the first letter of the match
+func parseLTSRules() {
+	ltsRules = make(map[byte][]ltsRule)
+	for _, rs := range EnglishLTSRules {
+		lb := strings.Index(rs, "[")
+		rb := strings.Index(rs, "]")
+		eq := strings.LastIndex(rs, "=")
+		rl := ltsRule{left: rs[:lb], match: rs[lb+1 : rb], right: rs[rb+1 : eq]}
+		rl.phones = splitARPAbet(rs[eq+1:])
+		ltsRules[rl.match[0]] = append(ltsRules[rl.match[0]], rl)
+	}
+}
+
+// splitARPAbet splits a string of ARPAbet phones without separators into the phones
+func splitARPAbet(s string) []string {
+	var phs []string
+	for len(s) > 0 {
+		if len(s) >= 2 {
+			if _, ok := ARPAbetPhones[s[:2]]; ok {
+				phs = append(phs, s[:2])
+				s = s[2:]
+				continue
+			}
+		}
+		phs = append(phs, s[:1])
+		s = s[1:]
+	}
+	return phs
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("AEIOUY", c) >= 0
+}
+
+func isConsonant(c byte) bool {
+	return c >= 'A' && c <= 'Z' && !isVowel(c)
+}
+
+// matchLeft returns true if the left context pattern matches the word before position pos
+func matchLeft(pat string, word string, pos int) bool {
+	for i := len(pat) - 1; i >= 0; i-- {
+		p := pat[i]
+		switch {
+		case p >= 'A' && p <= 'Z':
+			if pos <= 0 || word[pos-1] != p {
+				return false
+			}
+			pos--
+		case p == ' ':
+			if pos > 0 && word[pos-1] != ' ' {
+				return false
+			}
+			if pos > 0 {
+				pos--
+			}
+		case p == '#':
+			if pos <= 0 || !isVowel(word[pos-1]) {
+				return false
+			}
+			for pos > 0 && isVowel(word[pos-1]) {
+				pos--
+			}
+		case p == ':':
+			for pos > 0 && isConsonant(word[pos-1]) {
+				pos--
+			}
+		case p == '^':
+			if pos <= 0 || !isConsonant(word[pos-1]) {
+				return false
+			}
+			pos--
+		case p == '.':
+			if pos <= 0 || strings.IndexByte("BDGJLMNRVWZ", word[pos-1]) < 0 {
+				return false
+			}
+			pos--
+		case p == '+':
+			if pos <= 0 || strings.IndexByte("EIY", word[pos-1]) < 0 {
+				return false
+			}
+			pos--
+		case p == '&':
+			if pos <= 0 {
+				return false
+			}
+			if pos >= 2 && (word[pos-2:pos] == "CH" || word[pos-2:pos] == "SH") {
+				pos -= 2
+			} else if strings.IndexByte("SCGZXJ", word[pos-1]) >= 0 {
+				pos--
+			} else {
+				return false
+			}
+		case p == '@':
+			if pos <= 0 {
+				return false
+			}
+			if pos >= 2 && (word[pos-2:pos] == "TH" || word[pos-2:pos] == "CH" || word[pos-2:pos] == "SH") {
+				pos -= 2
+			} else if strings.IndexByte("TSRDLZNJ", word[pos-1]) >= 0 {
+				pos--
+			} else {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// matchRight returns true if the right context pattern matches the word from position pos
+func matchRight(pat string, word string, pos int) bool {
+	n := len(word)
+	for i := 0; i < len(pat); i++ {
+		p := pat[i]
+		switch {
+		case p >= 'A' && p <= 'Z':
+			if pos >= n || word[pos] != p {
+				return false
+			}
+			pos++
+		case p == ' ':
+			if pos < n && word[pos] != ' ' {
+				return false
+			}
+			if pos < n {
+				pos++
+			}
+		case p == '#':
+			if pos >= n || !isVowel(word[pos]) {
+				return false
+			}
+			for pos < n && isVowel(word[pos]) {
+				pos++
+			}
+		case p == ':':
+			for pos < n && isConsonant(word[pos]) {
+				pos++
+			}
+		case p == '^':
+			if pos >= n || !isConsonant(word[pos]) {
+				return false
+			}
+			pos++
+		case p == '.':
+			if pos >= n || strings.IndexByte("BDGJLMNRVWZ", word[pos]) < 0 {
+				return false
+			}
+			pos++
+		case p == '+':
+			if pos >= n || strings.IndexByte("EIY", word[pos]) < 0 {
+				return false
+			}
+			pos++
+		case p == '%':
+			sfx := ""
+			for _, s := range []string{"ING", "ELY", "ER", "ES", "ED", "E"} {
+				if strings.HasPrefix(word[pos:], s) {
+					sfx = s
+					break
+				}
+			}
+			if sfx == "" {
+				return false
+			}
+			pos += len(sfx)
+		case p == '&':
+			if strings.HasPrefix(word[pos:], "CH") || strings.HasPrefix(word[pos:], "SH") {
+				pos += 2
+			} else if pos < n && strings.IndexByte("SCGZXJ", word[pos]) >= 0 {
+				pos++
+			} else {
+				return false
+			}
+		case p == '@':
+			if strings.HasPrefix(word[pos:], "TH") || strings.HasPrefix(word[pos:], "CH") || strings.HasPrefix(word[pos:], "SH") {
+				pos += 2
+			} else if pos < n && strings.IndexByte("TSRDLZNJ", word[pos]) >= 0 {
+				pos++
+			} else {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LetterToSoundARPAbet converts the word to a sequence of ARPAbet phones using the EnglishLTSRules --
+// characters other than letters are ignored
+func LetterToSoundARPAbet(word string) []string {
+	ltsOnce.Do(parseLTSRules)
+	var sb strings.Builder
+	sb.WriteByte(' ')
+	for _, r := range strings.ToUpper(word) {
+		if r >= 'A' && r <= 'Z' {
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte(' ')
+	w := sb.String()
+	var phs []string
+	for pos := 1; pos < len(w)-1; {
+		matched := false
+		for _, rl := range ltsRules[w[pos]] {
+			if !strings.HasPrefix(w[pos:], rl.match) {
+				continue
+			}
+			if !matchLeft(rl.left, w, pos) || !matchRight(rl.right, w, pos+len(rl.match)) {
+				continue
+			}
+			phs = append(phs, rl.phones...)
+			pos += len(rl.match)
+			matched = true
+			break
+		}
+		if !matched { // every letter has a default rule, so this should not happen
+			pos++
+		}
+	}
+	return phs
+}
+
+// LetterToSound converts the word to a string of phones of EnglishPhones, in the format used by
+// SynthPhones (see PhoneSeq), using the EnglishLTSRules: the phones are divided into syllables, one
+// for each vowel, with a single consonant between vowels (or a cluster that can begin a syllable)
+// starting the next syllable, and the first syllable that does not have a reduced vowel (AX) is stressed
+func LetterToSound(word string) string {
+	raw := LetterToSoundARPAbet(word)
+	if len(raw) == 0 {
+		return ""
+	}
+	var aps []string
+	for i, ap := range raw { // doubled letters give repeated phones, e.g., happy
+		if i == 0 || ap != raw[i-1] {
+			aps = append(aps, ap)
+		}
+	}
+	var nuc []int
+	for i, ap := range aps {
+		if arpaVowels[ap] {
+			nuc = append(nuc, i)
+		}
+	}
+	stress := -1
+	for i, ni := range nuc {
+		if aps[ni] != "AX" {
+			stress = i
+			break
+		}
+	}
+	if stress < 0 && len(nuc) > 0 {
+		stress = 0
+	}
+
+	// syllable boundaries: index of the first phone of each syllable after the first
+	var bnds []int
+	for i := 1; i < len(nuc); i++ {
+		st := nuc[i-1] + 1
+		ed := nuc[i] // consonants between are st..ed-1
+		nc := ed - st
+		switch {
+		case nc <= 1:
+			bnds = append(bnds, st)
+		case arpaOnsets[aps[ed-2]+" "+aps[ed-1]]:
+			bnds = append(bnds, ed-2)
+		default:
+			bnds = append(bnds, ed-1)
+		}
+	}
+
+	var sb strings.Builder
+	bi := 0
+	ni := 0
+	for i, ap := range aps {
+		if bi < len(bnds) && bnds[bi] == i {
+			sb.WriteByte('.')
+			bi++
+		} else if i > 0 {
+			sb.WriteByte('_')
+		}
+		if arpaVowels[ap] {
+			if ni == stress {
+				sb.WriteByte('\'')
+			}
+			ni++
+		}
+		if ph, ok := ARPAbetPhones[ap]; ok {
+			sb.WriteString(ph)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLetterToSoundARPAbet(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"cat", "K AE T"},
+		{"speech", "S P IY CH"},
+		{"phone", "F OW N"},
+		{"nation", "N EY SH AX N"},
+		{"thought", "TH AO T"},
+		{"strange", "S T R EY N J"},
+		{"knight", "N AY T"},
+		{"Cat's", "K AE T S"},
+		{"", ""},
+		{"123", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(LetterToSoundARPAbet(tt.word), " "); got != tt.want {
+			t.Errorf("LetterToSoundARPAbet(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestLetterToSound(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"cat", "k_'a_t"},
+		{"hello", "h_'e.l_er_u"},
+		{"happy", "h_'a.p_i"},           // doubled letters give one phone
+		{"station", "s_t_'e_i.sh_uh_n"}, // st starts the syllable
+		{"table", "t_'e_i.b_uh_l"},
+		{"yesterday", "y_'e.s_t_er.d_e_i"},
+		{"the", "dh_'uh"}, // only a reduced vowel, which is stressed
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := LetterToSound(tt.word); got != tt.want {
+			t.Errorf("LetterToSound(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestLetterToSoundPhones(t *testing.T) {
+	for _, rs := range EnglishLTSRules {
+		for _, ap := range splitARPAbet(rs[strings.LastIndex(rs, "=")+1:]) {
+			if _, ok := ARPAbetPhones[ap]; !ok {
+				t.Errorf("rule %q: ARPAbet phone %q has no English phone", rs, ap)
+			}
+		}
+	}
+	var vt VocalTract
+	words := strings.Fields("a hello speech synthesis computer about water running believe musical language through quickly judge voice whether")
+	for _, w := range words {
+		phs := LetterToSound(w)
+		if _, ok := vt.PhoneSeq(phs, nil); !ok {
+			t.Errorf("LetterToSound(%q) = %q, which has phones not in the phone table", w, phs)
+		}
+	}
+}
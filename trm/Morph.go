@@ -0,0 +1,110 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+)
+
+// VoiceMorph morphs the voice continuously from one set of VoiceParams to another over time,
+// e.g., to change the tract length, glottal pulse, pitch reference and breathiness within an utterance --
+// the params are interpolated at the start of each control period, and as the tract length changes
+// the internal sample rate changes with it, while the output stays at the output rate
+type VoiceMorph struct {
+	On   bool
+	From VoiceParams // voice at the start of the morph
+	To   VoiceParams // voice at the end of the morph, which is kept after the morph is complete
+	Dur  float32     // duration of the morph in ms
+	Time float32     // time in ms since the start of the morph
+}
+
+// Voice returns the voice params at the current Time
+func (vm *VoiceMorph) Voice() VoiceParams {
+	t := float32(1)
+	if vm.Dur > 0 {
+		t = vm.Time / vm.Dur
+	}
+	if t > 1 {
+		t = 1
+	} else if t < 0 {
+		t = 0
+	}
+	return LerpVoice(&vm.From, &vm.To, t)
+}
+
+// LerpVoice returns the voice params interpolated linearly between from (t = 0) and to (t = 1)
+func LerpVoice(from, to *VoiceParams, t float32) VoiceParams {
+	lerp := func(a, b float32) float32 { return a + t*(b-a) }
+	vp := *from
+	vp.TractLength = lerp(from.TractLength, to.TractLength)
+	vp.GlotPulseFallMin = lerp(from.GlotPulseFallMin, to.GlotPulseFallMin)
+	vp.GlotPulseFallMax = lerp(from.GlotPulseFallMax, to.GlotPulseFallMax)
+	vp.GlotPitchRef = lerp(from.GlotPitchRef, to.GlotPitchRef)
+	vp.Breathiness = lerp(from.Breathiness, to.Breathiness)
+	vp.GlotPulseRise = lerp(from.GlotPulseRise, to.GlotPulseRise)
+	vp.ApertureRadius = lerp(from.ApertureRadius, to.ApertureRadius)
+	for i := range vp.NoseRadii {
+		vp.NoseRadii[i] = lerp(from.NoseRadii[i], to.NoseRadii[i])
+	}
+	vp.Radius1 = lerp(from.Radius1, to.Radius1)
+	vp.NoseRadiusCoef = lerp(from.NoseRadiusCoef, to.NoseRadiusCoef)
+	vp.RadiusCoef = lerp(from.RadiusCoef, to.RadiusCoef)
+	return vp
+}
+
+// StartMorph starts morphing the voice from one set of params to another over dur ms of synthesis
+func (vt *VocalTract) StartMorph(from, to *VoiceParams, dur float32) {
+	vt.Morph.From = *from
+	vt.Morph.To = *to
+	vt.Morph.Dur = dur
+	vt.Morph.Time = 0
+	vt.Morph.On = true
+	vt.Voice = *from
+	vt.UpdateVoice()
+}
+
+// PitchRef returns the reference pitch of the voice for the next control period, i.e., of the morphed voice if morphing
+func (vt *VocalTract) PitchRef() float32 {
+	if vt.Morph.On {
+		return vt.Morph.Voice().GlotPitchRef
+	}
+	return vt.Voice.GlotPitchRef
+}
+
+// MorphStep sets the Voice to the morphed voice for the next control period and updates the synthesizer for it
+func (vt *VocalTract) MorphStep() {
+	vt.Voice = vt.Morph.Voice()
+	vt.UpdateVoice()
+	vt.Morph.Time += vt.Duration
+}
+
+// UpdateVoice updates the internal sample rate and control period, and everything that depends on them
+// and the Voice, without resetting the state of the synthesizer, so the Voice can change during synthesis
+func (vt *VocalTract) UpdateVoice() {
+	if vt.Voice.TractLength <= 0 || vt.ControlRate <= 0 {
+		return
+	}
+	c := SpeedOfSound(vt.Config.Temp)
+	nSects := float32(vt.SectCount())
+	vt.ControlPeriod = int(math.Round(float64(c*nSects*100.0) / float64(vt.Voice.TractLength*vt.ControlRate)))
+	vt.SampleRate = int(vt.ControlRate * float32(vt.ControlPeriod))
+	vt.ActualTubeLength = float32(c*nSects*100.0) / float32(vt.SampleRate)
+	nyquist := float32(vt.SampleRate) / 2.0
+
+	vt.BreathinessFactor = vt.Voice.Breathiness / 100.0
+	vt.GlottalSource.SetVoice(vt.Config.WaveForm, float32(vt.SampleRate), vt.Voice.GlotPulseRise, vt.Voice.GlotPulseFallMin, vt.Voice.GlotPulseFallMax)
+	vt.PrevGlotAmplitude = -1.0 // recompute the pulse for the current amplitude
+
+	mouthApertureCoef := (nyquist - vt.Config.MouthCoef) / nyquist
+	vt.MouthRadiationFilter.SetCoef(mouthApertureCoef)
+	vt.MouthReflectionFilter.SetCoef(mouthApertureCoef)
+	nasalApertureCoef := (nyquist - vt.Config.NoseCoef) / nyquist
+	vt.NasalRadiationFilter.SetCoef(nasalApertureCoef)
+	vt.NasalReflectionFilter.SetCoef(nasalApertureCoef)
+
+	vt.InitializeNasalCavity()
+	vt.Throat.Init(float32(vt.SampleRate), vt.Config.ThroatCutoff, Amplitude(vt.Config.ThroatVol))
+	vt.SampleRateConverter.SetSampleRate(vt.SampleRate, float32(vt.Config.OutputRate))
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+// voiceVals returns all of the voice params as a slice
+func voiceVals(vp *VoiceParams) []float32 {
+	vals := []float32{vp.TractLength, vp.GlotPulseFallMin, vp.GlotPulseFallMax, vp.GlotPitchRef, vp.Breathiness,
+		vp.GlotPulseRise, vp.ApertureRadius, vp.Radius1, vp.NoseRadiusCoef, vp.RadiusCoef}
+	return append(vals, vp.NoseRadii[:]...)
+}
+
+func TestLerpVoice(t *testing.T) {
+	var from, to VoiceParams
+	from.SetDefault(Male)
+	to.SetDefault(Baby)
+	to.NoseRadii[2] = 2.5
+	to.RadiusCoef = 0.8
+	fv, tv := voiceVals(&from), voiceVals(&to)
+	for _, tm := range []float32{0, 0.25, 1} {
+		vp := LerpVoice(&from, &to, tm)
+		for i, v := range voiceVals(&vp) {
+			want := fv[i] + tm*(tv[i]-fv[i])
+			if math.Abs(float64(v-want)) > 1e-5 {
+				t.Errorf("t = %v: param %d = %v, want %v", tm, i, v, want)
+			}
+		}
+	}
+}
+
+func TestVoiceMorph(t *testing.T) {
+	var vm VoiceMorph
+	vm.From.SetDefault(Male)
+	vm.To.SetDefault(Female)
+	vm.Dur = 100
+	tests := []struct {
+		time, tl float32
+	}{
+		{-10, vm.From.TractLength},
+		{0, vm.From.TractLength},
+		{50, 0.5 * (vm.From.TractLength + vm.To.TractLength)},
+		{100, vm.To.TractLength},
+		{200, vm.To.TractLength},
+	}
+	for _, tt := range tests {
+		vm.Time = tt.time
+		if tl := vm.Voice().TractLength; math.Abs(float64(tl-tt.tl)) > 1e-5 {
+			t.Errorf("TractLength at %v ms = %v, want %v", tt.time, tl, tt.tl)
+		}
+	}
+	vm.Dur = 0
+	vm.Time = 0
+	if tl := vm.Voice().TractLength; tl != vm.To.TractLength {
+		t.Errorf("TractLength with Dur 0 = %v, want %v", tl, vm.To.TractLength)
+	}
+}
+
+func TestMorphSynth(t *testing.T) {
+	vt := newTestVocalTract(t)
+	var from, to VoiceParams
+	from.SetDefault(Male)
+	to.SetDefault(Baby)
+	vt.StartMorph(&from, &to, 500)
+	if pr := vt.PitchRef(); pr != from.GlotPitchRef {
+		t.Errorf("PitchRef at the start of the morph = %v, want %v", pr, from.GlotPitchRef)
+	}
+	startRate := vt.SampleRate
+	vt.CurControl.Init()
+	vt.CurControl.GlotVol = 60
+	const nPer = 30 // 750 ms
+	for i := 0; i < nPer; i++ {
+		if err := vt.Synthesize(false); err != nil {
+			t.Fatalf("Synthesize error: %v", err)
+		}
+	}
+	vt.FlushStream()
+	if math.Abs(float64(vt.Voice.TractLength-to.TractLength)) > 1e-5 {
+		t.Errorf("TractLength after the morph = %v, want %v", vt.Voice.TractLength, to.TractLength)
+	}
+	if !(vt.SampleRate > startRate) {
+		t.Errorf("internal sample rate = %d after the morph to a shorter tract, not above %d", vt.SampleRate, startRate)
+	}
+	// the output rate is unchanged, apart from the flushed padding of the converter
+	want := nPer * vt.Duration / 1000 * float32(vt.Config.OutputRate)
+	if n := float32(len(vt.OutputData)); n < want || n > want+100 {
+		t.Errorf("%v output samples, want %v", n, want)
+	}
+	for i, v := range vt.OutputData {
+		if v != v {
+			t.Fatalf("output sample %d is NaN", i)
+		}
+	}
+}
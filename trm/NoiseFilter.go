@@ -35,6 +35,9 @@ func (nf *NoiseFilter) Reset() {
 	nf.NoiseX = 0.0
 }
 
+// Filter is a one-zero lowpass filter of the noise
 func (nf *NoiseFilter) Filter(input float32) float32 {
-	return input + nf.NoiseX
+	output := input + nf.NoiseX
+	nf.NoiseX = input
+	return output
 }
@@ -0,0 +1,61 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNoiseFilter(t *testing.T) {
+	var nf NoiseFilter
+	nf.Reset()
+	want := []float32{1, 1, 0, 0}
+	for i, w := range want {
+		in := float32(0)
+		if i == 0 {
+			in = 1
+		}
+		if got := nf.Filter(in); got != w {
+			t.Errorf("impulse response %d = %v, want %v", i, got, w)
+		}
+	}
+	nf.Reset()
+	nf.Filter(1)
+	nf.Reset()
+	if got := nf.Filter(0); got != 0 {
+		t.Errorf("output after Reset = %v, want 0", got)
+	}
+}
+
+func TestNoiseSource(t *testing.T) {
+	var ns NoiseSource
+	ns.Init()
+	// seed = frac(.7892347 * 377) = .5414819
+	if got := ns.GetSample(); math.Abs(float64(got)-0.0414819) > 1e-4 {
+		t.Errorf("first sample = %v, want 0.0414819", got)
+	}
+	ns.Reset()
+	const n = 10000
+	first := make([]float32, n)
+	sum := 0.0
+	for i := range first {
+		v := ns.GetSample()
+		if v < -0.5 || v >= 0.5 {
+			t.Fatalf("sample %d = %v, outside of [-0.5, 0.5)", i, v)
+		}
+		first[i] = v
+		sum += float64(v)
+	}
+	if mean := sum / n; math.Abs(mean) > 0.02 {
+		t.Errorf("mean of %d samples = %v, want about 0", n, mean)
+	}
+	ns.Reset()
+	for i, w := range first[:100] {
+		if got := ns.GetSample(); got != w {
+			t.Fatalf("sample %d after Reset = %v, want %v", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"github.com/chewxy/math32"
+)
+
+// ToneGroups are the types of intonation contours of a tone group (phrase)
+type ToneGroups int32
+
+const (
+	// Statement has a final fall, e.g., at a period
+	Statement = iota
+
+	// Question has a final rise, e.g., at a question mark
+	Question
+
+	// Continuation has a slight final rise, e.g., at a comma
+	Continuation
+
+	ToneGroupsN
+)
+
+//go:generate stringer -type=ToneGroups
+
+// Prosody are the parameters for phrase-level prosody: tone groups of phones are divided into
+// feet, each starting with a stressed syllable, whose durations are adjusted toward a common
+// foot duration (stress-timed rhythm), and an F0 (pitch) contour is generated with a declining
+// baseline, an accent on each stressed syllable and a final fall or rise according to the type of tone group
+type Prosody struct {
+	On          bool
+	FootDur     float32 // target duration of a foot in ms
+	Isochrony   float32 // how far each foot duration is moved toward FootDur (0-1) -- 0 is no rhythm adjustment
+	MinScale    float32 // minimum scaling of the durations of the phones in a foot
+	MaxScale    float32 // maximum scaling of the durations of the phones in a foot
+	FinalLen    float32 // duration multiplier for the last syllable of a tone group (phrase-final lengthening)
+	Declination float32 // fall of the baseline pitch in semitones per second
+	Accent      float32 // pitch accent on stressed (') syllables in semitones
+	DblAccent   float32 // pitch accent on double stressed (") syllables in semitones
+	FinalFall   float32 // final fall in semitones at the end of a Statement
+	FinalRise   float32 // final rise in semitones at the end of a Question
+	ContRise    float32 // final rise in semitones at the end of a Continuation
+	PhrasePause float32 // duration in ms of the pause after a phrase ending in , ; or :
+	SentPause   float32 // duration in ms of the pause after a sentence ending in . ! or ?
+}
+
+// Defaults sets the default prosody params
+func (pr *Prosody) Defaults() {
+	pr.On = true
+	pr.FootDur = 400
+	pr.Isochrony = 0.5
+	pr.MinScale = 0.7
+	pr.MaxScale = 1.4
+	pr.FinalLen = 1.3
+	pr.Declination = 2
+	pr.Accent = 2
+	pr.DblAccent = 4
+	pr.FinalFall = -4
+	pr.FinalRise = 6
+	pr.ContRise = 2
+	pr.PhrasePause = 150
+	pr.SentPause = 350
+}
+
+// syllable is the range of phones [st, ed] of a syllable within a sequence
+type syllable struct {
+	st, ed int
+	stress bool
+	dbl    bool
+}
+
+// syllables returns the syllables of the tone group of phones [st, ed] -- silences are not part of any syllable
+func syllables(seq []Phone, st, ed int) []syllable {
+	var syls []syllable
+	cur := syllable{st: -1}
+	for i := st; i <= ed; i++ {
+		ph := &seq[i]
+		if ph.Posture.HasCat("silence") {
+			if cur.st >= 0 {
+				syls = append(syls, cur)
+				cur = syllable{st: -1}
+			}
+			continue
+		}
+		if cur.st < 0 {
+			cur.st = i
+		}
+		cur.ed = i
+		cur.stress = cur.stress || ph.Stress || ph.DblStress
+		cur.dbl = cur.dbl || ph.DblStress
+		if ph.SylEnd || i == ed {
+			syls = append(syls, cur)
+			cur = syllable{st: -1}
+		}
+	}
+	return syls
+}
+
+// ToneGroupEnds returns the index of the last phone of each tone group in the sequence
+func ToneGroupEnds(seq []Phone) []int {
+	var ends []int
+	for i := range seq {
+		if seq[i].GroupEnd || i == len(seq)-1 {
+			ends = append(ends, i)
+		}
+	}
+	return ends
+}
+
+// Rhythm adjusts the durations of the phones of the sequence, prior to Timing: within each tone group,
+// the phones of each foot are scaled so the foot duration moves toward FootDur, and the last
+// syllable is lengthened by FinalLen
+func (pr *Prosody) Rhythm(seq []Phone) {
+	st := 0
+	for _, ed := range ToneGroupEnds(seq) {
+		syls := syllables(seq, st, ed)
+		st = ed + 1
+		if len(syls) == 0 {
+			continue
+		}
+		for si := 0; si < len(syls); si++ {
+			if !syls[si].stress {
+				continue
+			}
+			fst := syls[si].st
+			fed := syls[si].ed
+			for si+1 < len(syls) && !syls[si+1].stress {
+				si++
+				fed = syls[si].ed
+			}
+			dur := float32(0)
+			for i := fst; i <= fed; i++ {
+				if !seq[i].Posture.HasCat("silence") {
+					dur += seq[i].Transition + seq[i].Duration
+				}
+			}
+			if dur <= 0 {
+				continue
+			}
+			trg := dur + pr.Isochrony*(pr.FootDur-dur)
+			scale := trg / dur
+			if scale < pr.MinScale {
+				scale = pr.MinScale
+			}
+			if scale > pr.MaxScale {
+				scale = pr.MaxScale
+			}
+			for i := fst; i <= fed; i++ {
+				if !seq[i].Posture.HasCat("silence") {
+					seq[i].Duration *= scale
+				}
+			}
+		}
+		lst := syls[len(syls)-1]
+		for i := lst.st; i <= lst.ed; i++ {
+			seq[i].Duration *= pr.FinalLen
+		}
+	}
+}
+
+// cosRamp returns a smooth (raised cosine) ramp from 0 at st to 1 at ed
+func cosRamp(t, st, ed float32) float32 {
+	if t <= st {
+		return 0
+	}
+	if t >= ed {
+		return 1
+	}
+	return 0.5 - 0.5*math32.Cos(math32.Pi*(t-st)/(ed-st))
+}
+
+// Contour adds the F0 contour, in semitones, to the GlotPitch of the trajectory, which has
+// one control state for the end of each stepMs, as generated by RuleSet.Trajectory --
+// Timing must have been called on the sequence. tgs is the type of each tone group in the sequence,
+// as delimited by GroupEnd -- if there are fewer types than groups the rest are Statements
+func (pr *Prosody) Contour(seq []Phone, tgs []ToneGroups, traj []VocalTractCtrl, stepMs float32) {
+	st := 0
+	for gi, ed := range ToneGroupEnds(seq) {
+		tg := ToneGroups(Statement)
+		if gi < len(tgs) {
+			tg = tgs[gi]
+		}
+		syls := syllables(seq, st, ed)
+		t0 := seq[st].Onset
+		lph := &seq[ed]
+		t1 := lph.Onset + lph.Transition + lph.Duration
+		st = ed + 1
+		if len(syls) == 0 {
+			continue
+		}
+
+		// final contour runs from the center of the last stressed syllable to the end of the group
+		fin := syls[len(syls)-1]
+		for si := len(syls) - 1; si >= 0; si-- {
+			if syls[si].stress {
+				fin = syls[si]
+				break
+			}
+		}
+		finSt := sylCenter(seq, fin)
+		finAmt := pr.FinalFall
+		switch tg {
+		case Question:
+			finAmt = pr.FinalRise
+		case Continuation:
+			finAmt = pr.ContRise
+		}
+
+		fst := int(t0 / stepMs)
+		fed := int(math32.Ceil(t1 / stepMs))
+		if fed > len(traj) {
+			fed = len(traj)
+		}
+		for f := fst; f < fed; f++ {
+			t := float32(f+1) * stepMs
+			if t < t0 || t > t1 {
+				continue
+			}
+			pitch := -pr.Declination * (t - t0) / 1000
+			for _, sy := range syls {
+				if !sy.stress {
+					continue
+				}
+				ctr := sylCenter(seq, sy)
+				hw := 0.5 * sylDur(seq, sy)
+				acc := pr.Accent
+				if sy.dbl {
+					acc = pr.DblAccent
+				}
+				if t > ctr-hw && t < ctr+hw {
+					pitch += acc * (0.5 + 0.5*math32.Cos(math32.Pi*(t-ctr)/hw))
+				}
+			}
+			pitch += finAmt * cosRamp(t, finSt, t1)
+			traj[f].GlotPitch += pitch
+		}
+	}
+}
+
+// sylCenter returns the time in ms of the center of the steady state of the vowel of the syllable,
+// or of the syllable as a whole if it has no vowel
+func sylCenter(seq []Phone, sy syllable) float32 {
+	for i := sy.st; i <= sy.ed; i++ {
+		ph := &seq[i]
+		if ph.Posture.HasCat("vowel") {
+			return ph.Onset + ph.Transition + 0.5*ph.Duration
+		}
+	}
+	return seq[sy.st].Onset + 0.5*sylDur(seq, sy)
+}
+
+// sylDur returns the duration of the syllable in ms
+func sylDur(seq []Phone, sy syllable) float32 {
+	lst := &seq[sy.ed]
+	return lst.Onset + lst.Transition + lst.Duration - seq[sy.st].Onset
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+// testSyllable returns a sequence of a consonant and a vowel (20 ms transitions, 40 and 100 ms
+// durations) as one syllable
+func testSyllable(stress bool) []Phone {
+	seq := testSeq(testPosture("k", []string{"consonant"}, 0, 20, 40), testPosture("a", []string{"vowel"}, 0, 20, 100))
+	seq[1].Stress = stress
+	seq[1].SylEnd = true
+	return seq
+}
+
+func TestToneGroupEnds(t *testing.T) {
+	seq := make([]Phone, 6)
+	seq[2].GroupEnd = true
+	seq[3].GroupEnd = true
+	want := []int{2, 3, 5}
+	got := ToneGroupEnds(seq)
+	if len(got) != len(want) {
+		t.Fatalf("ToneGroupEnds = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ToneGroupEnds = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRhythm(t *testing.T) {
+	var pr Prosody
+	pr.Defaults()
+	seq := testSeq(testPosture("k", []string{"consonant"}, 0, 20, 40), testPosture("a", []string{"vowel"}, 0, 20, 100),
+		testPosture("t", []string{"consonant"}, 0, 20, 40), testPosture("i", []string{"vowel"}, 0, 20, 60),
+		testPosture("#", []string{"silence"}, 0, 20, 80))
+	seq[1].Stress = true
+	seq[1].SylEnd = true
+	seq[3].SylEnd = true
+	pr.Rhythm(seq)
+	// one foot of 320 ms is moved half way to 400 ms, and the last syllable is lengthened by 1.3
+	scale := float32(360.0 / 320.0)
+	want := []float32{40 * scale, 100 * scale, 40 * scale * 1.3, 60 * scale * 1.3, 80}
+	for i, w := range want {
+		if math.Abs(float64(seq[i].Duration-w)) > 1e-3 {
+			t.Errorf("phone %d duration = %v, want %v", i, seq[i].Duration, w)
+		}
+	}
+
+	// a short foot is scaled by at most MaxScale
+	seq = testSyllable(true)
+	pr.FinalLen = 1
+	pr.Rhythm(seq)
+	if math.Abs(float64(seq[1].Duration-100*pr.MaxScale)) > 1e-3 {
+		t.Errorf("duration of a short foot = %v, want %v", seq[1].Duration, 100*pr.MaxScale)
+	}
+}
+
+func TestContour(t *testing.T) {
+	var pr Prosody
+	pr.Defaults()
+	const stepMs = 5
+	final := make(map[ToneGroups]float32)
+	for tg := ToneGroups(Statement); tg < ToneGroupsN; tg++ {
+		seq := testSyllable(true)
+		traj := make([]VocalTractCtrl, 36) // 180 ms
+		pr.Contour(seq, []ToneGroups{tg}, traj, stepMs)
+		if tg == Statement {
+			// accent peak at the center of the vowel at 130 ms, on the declining baseline
+			want := -pr.Declination*130/1000 + pr.Accent
+			if got := traj[25].GlotPitch; math.Abs(float64(got-want)) > 1e-4 {
+				t.Errorf("pitch at the center of the stressed vowel = %v, want %v", got, want)
+			}
+		}
+		final[tg] = traj[len(traj)-1].GlotPitch
+	}
+	if d := final[Question] - final[Statement]; math.Abs(float64(d-(pr.FinalRise-pr.FinalFall))) > 1e-4 {
+		t.Errorf("final Question - Statement pitch = %v, want %v", d, pr.FinalRise-pr.FinalFall)
+	}
+	if d := final[Continuation] - final[Statement]; math.Abs(float64(d-(pr.ContRise-pr.FinalFall))) > 1e-4 {
+		t.Errorf("final Continuation - Statement pitch = %v, want %v", d, pr.ContRise-pr.FinalFall)
+	}
+
+	// without stress there is no accent, and the contour adds to the trajectory pitch
+	seq := testSyllable(false)
+	traj := make([]VocalTractCtrl, 36)
+	for i := range traj {
+		traj[i].GlotPitch = 3
+	}
+	pr.Contour(seq, nil, traj, stepMs)
+	want := 3 - pr.Declination*20/1000
+	if got := traj[3].GlotPitch; math.Abs(float64(got-want)) > 1e-4 {
+		t.Errorf("unstressed pitch at 20 ms = %v, want %v", got, want)
+	}
+	want = 3 - pr.Declination*180/1000 + pr.FinalFall
+	if got := traj[35].GlotPitch; math.Abs(float64(got-want)) > 1e-4 {
+		t.Errorf("unstressed final pitch = %v, want %v", got, want)
+	}
+}
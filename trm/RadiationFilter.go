@@ -39,8 +39,15 @@ func (rf *RadiationFilter) Init(apertureCoef float32) {
 	rf.RadiationX = 0.0
 	rf.RadiationY = 0.0
 	rf.A20 = apertureCoef
-	rf.A21 = rf.A20
-	rf.B21 = rf.A20
+	rf.A21 = -rf.A20
+	rf.B21 = -rf.A20
+}
+
+// SetCoef sets the filter coefficients for the aperture coefficient, without resetting the filter
+func (rf *RadiationFilter) SetCoef(apertureCoef float32) {
+	rf.A20 = apertureCoef
+	rf.A21 = -rf.A20
+	rf.B21 = -rf.A20
 }
 
 func (rf *RadiationFilter) Reset() {
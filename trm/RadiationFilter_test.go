@@ -0,0 +1,45 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRadiationFilter(t *testing.T) {
+	const a = 0.5
+	var rf RadiationFilter
+	rf.Init(a)
+	// y[n] = a * (x[n] - x[n-1] + y[n-1])
+	want := []float32{a, a * (a - 1), a * a * (a - 1)}
+	for i, w := range want {
+		in := float32(0)
+		if i == 0 {
+			in = 1
+		}
+		if got := rf.Filter(in); math.Abs(float64(got-w)) > 1e-6 {
+			t.Errorf("impulse response %d = %v, want %v", i, got, w)
+		}
+	}
+
+	// highpass: the response to a step decays to 0
+	rf.Reset()
+	var y float32
+	for i := 0; i < 100; i++ {
+		y = rf.Filter(1)
+	}
+	if math.Abs(float64(y)) > 1e-6 {
+		t.Errorf("step response after 100 samples = %v, want 0", y)
+	}
+
+	rf.SetCoef(0.25)
+	if rf.A20 != 0.25 || rf.A21 != -0.25 || rf.B21 != -0.25 {
+		t.Errorf("SetCoef(0.25) coefficients = %v, %v, %v, want 0.25, -0.25, -0.25", rf.A20, rf.A21, rf.B21)
+	}
+	if rf.RadiationX != 1 {
+		t.Errorf("SetCoef reset the filter state")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"errors"
+	"sync"
+)
+
+// RealTime provides interactive control of a VocalTract, e.g., by an agent learning to babble:
+// the control parameter targets can be set at any time, from any goroutine, and the output is
+// pulled a given number of samples at a time, synthesizing as many short control periods as needed.
+// Targets take effect at the start of the next control period, and the control parameters move
+// linearly to them over the period (limited by DeltaMax if RateLimit is set on the VocalTract).
+type RealTime struct {
+	VT      *VocalTract
+	CtrlMs  float32        // control period in ms, which sets the latency of target updates
+	mu      sync.Mutex     // guards target
+	synMu   sync.Mutex     // guards synthesis and pending
+	target  VocalTractCtrl // current targets
+	pending []float32      // output samples synthesized but not yet pulled
+}
+
+// Init initializes real-time control of the vocal tract with a control period of ctrlMs ms
+// (e.g., 5) -- the vocal tract is reinitialized, and its output is streamed to the RealTime,
+// so it no longer accumulates in OutputData -- returns the InitSynth error, if any, in which
+// case the RealTime is not initialized
+func (rt *RealTime) Init(vt *VocalTract, ctrlMs float32) error {
+	rt.synMu.Lock()
+	defer rt.synMu.Unlock()
+	vt.Duration = ctrlMs
+	if err := vt.InitSynth(); err != nil {
+		return err
+	}
+	rt.VT = vt
+	rt.CtrlMs = ctrlMs
+	vt.StreamTo(rt.collect, 0)
+	rt.pending = rt.pending[:0]
+	rt.mu.Lock()
+	rt.target = vt.CurControl
+	rt.mu.Unlock()
+	return nil
+}
+
+// collect is the Sink for the output of the vocal tract
+func (rt *RealTime) collect(samples []float32) {
+	rt.pending = append(rt.pending, samples...)
+}
+
+// SetTarget sets the targets of all the control parameters
+func (rt *RealTime) SetTarget(ctrl *VocalTractCtrl) {
+	rt.mu.Lock()
+	rt.target = *ctrl
+	rt.mu.Unlock()
+}
+
+// SetParam sets the target of one control parameter -- returns a CtrlIdxError, without
+// setting anything, if idx is not a valid control parameter
+func (rt *RealTime) SetParam(idx CtrlParamIdxs, val float32) error {
+	if idx < 0 || idx >= NCtrlParams {
+		return &CtrlIdxError{Idx: idx}
+	}
+	rt.mu.Lock()
+	*rt.target.ParamVal(int(idx)) = val
+	rt.mu.Unlock()
+	return nil
+}
+
+// Target returns the current targets of the control parameters
+func (rt *RealTime) Target() VocalTractCtrl {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.target
+}
+
+// Pull fills dst with the next len(dst) output samples, at the output rate of the vocal tract,
+// synthesizing toward the current targets as needed -- returns an error if the targets are
+// out of range, unless ClampCtrl is set on the VocalTract (see VocalTract.CheckCtrl)
+func (rt *RealTime) Pull(dst []float32) error {
+	rt.synMu.Lock()
+	defer rt.synMu.Unlock()
+	if rt.VT == nil {
+		return errors.New("trm.RealTime.Pull: not initialized")
+	}
+	for len(rt.pending) < len(dst) {
+		rt.VT.CurControl = rt.Target()
+		if err := rt.VT.Synthesize(false); err != nil {
+			return err
+		}
+		rt.VT.SampleRateConverter.Drain()
+	}
+	n := copy(dst, rt.pending)
+	rt.pending = rt.pending[:copy(rt.pending, rt.pending[n:])]
+	return nil
+}
+
+// Reset resets the vocal tract, discarding any output not yet pulled -- the targets are kept
+func (rt *RealTime) Reset() error {
+	rt.synMu.Lock()
+	defer rt.synMu.Unlock()
+	if rt.VT == nil {
+		return errors.New("trm.RealTime.Reset: not initialized")
+	}
+	rt.VT.SynthReset(true)
+	rt.pending = rt.pending[:0]
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"sync"
+	"testing"
+)
+
+// maxAbs returns the max absolute value of x
+func maxAbs(x []float32) float32 {
+	var mx float32
+	for _, v := range x {
+		if v > mx {
+			mx = v
+		} else if -v > mx {
+			mx = -v
+		}
+	}
+	return mx
+}
+
+// newTestRealTime returns a RealTime with a 5 ms control period, controlling a new VocalTract
+func newTestRealTime(t *testing.T) *RealTime {
+	t.Helper()
+	vt := newTestVocalTract(t)
+	vt.CurControl.Init()
+	rt := &RealTime{}
+	if err := rt.Init(vt, 5); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	return rt
+}
+
+func TestRealTimePull(t *testing.T) {
+	rt := newTestRealTime(t)
+	buf := make([]float32, 441) // 10 ms
+	for i := 0; i < 5; i++ {
+		if err := rt.Pull(buf); err != nil {
+			t.Fatalf("Pull error: %v", err)
+		}
+	}
+	if mx := maxAbs(buf); mx != 0 {
+		t.Errorf("output with no glottal volume = %v, want 0", mx)
+	}
+	rt.SetParam(GlotVolIdx, 60)
+	for i := 0; i < 5; i++ {
+		if err := rt.Pull(buf); err != nil {
+			t.Fatalf("Pull error: %v", err)
+		}
+	}
+	if mx := maxAbs(buf); mx == 0 {
+		t.Errorf("no output 50 ms after setting the glottal volume")
+	}
+	if len(rt.VT.OutputData) != 0 {
+		t.Errorf("%d samples in OutputData, want 0 as the output is pulled", len(rt.VT.OutputData))
+	}
+
+	// pulls of any size are served from the pending samples
+	for _, n := range []int{1, 7, 1000, 3} {
+		if err := rt.Pull(make([]float32, n)); err != nil {
+			t.Fatalf("Pull of %d samples error: %v", n, err)
+		}
+	}
+
+	for _, idx := range []CtrlParamIdxs{-1, NCtrlParams} {
+		err := rt.SetParam(idx, 1)
+		if ie, ok := err.(*CtrlIdxError); !ok || ie.Idx != idx {
+			t.Errorf("SetParam(%d) error = %v, want a CtrlIdxError", idx, err)
+		}
+	}
+	if err := rt.Reset(); err != nil {
+		t.Errorf("Reset error: %v", err)
+	}
+	if err := rt.Pull(buf); err != nil {
+		t.Fatalf("Pull after Reset error: %v", err)
+	}
+
+	rt.SetParam(GlotVolIdx, 100)
+	if err := rt.Pull(buf); err == nil {
+		t.Errorf("Pull with a target out of range: no error")
+	}
+	rt.VT.ClampCtrl = true
+	if err := rt.Pull(buf); err != nil {
+		t.Errorf("Pull with a target out of range and ClampCtrl: error %v", err)
+	}
+	if tg := rt.Target(); tg.GlotVol != 100 {
+		t.Errorf("target GlotVol = %v, want 100 -- the targets are not clamped", tg.GlotVol)
+	}
+}
+
+func TestRealTimeInit(t *testing.T) {
+	var rt RealTime
+	if err := rt.Pull(make([]float32, 10)); err == nil {
+		t.Errorf("Pull before Init: no error")
+	}
+	if err := rt.Reset(); err == nil {
+		t.Errorf("Reset before Init: no error")
+	}
+	vt := newTestVocalTract(t)
+	vt.Voice.TractLength = 0
+	if err := rt.Init(vt, 5); err == nil || rt.VT != nil {
+		t.Errorf("Init with zero TractLength: error %v and VT %v, want an error and nil", err, rt.VT)
+	}
+}
+
+// TestRealTimeConcurrent sets the targets from other goroutines while pulling the output --
+// run with -race to check the locking
+func TestRealTimeConcurrent(t *testing.T) {
+	rt := newTestRealTime(t)
+	rt.SetParam(GlotVolIdx, 60)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				rt.SetParam(GlotPitchIdx, float32(i%10-g))
+				if i%50 == 0 {
+					tg := rt.Target()
+					tg.Velum = 0.5
+					rt.SetTarget(&tg)
+				}
+			}
+		}(g)
+	}
+	buf := make([]float32, 441)
+	for i := 0; i < 20; i++ {
+		if err := rt.Pull(buf); err != nil {
+			t.Fatalf("Pull error: %v", err)
+		}
+		for j, v := range buf {
+			if v != v {
+				t.Fatalf("Pull %d: sample %d is NaN", i, j)
+			}
+		}
+	}
+	wg.Wait()
+	rt.SetParam(GlotPitchIdx, -5)
+	if tg := rt.Target(); tg.GlotPitch != -5 || tg.GlotVol != 60 || tg.Velum != 0.5 {
+		t.Errorf("target pitch, volume and velum = %v, %v, %v, want -5, 60, 0.5", tg.GlotPitch, tg.GlotVol, tg.Velum)
+	}
+}
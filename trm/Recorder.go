@@ -0,0 +1,104 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+)
+
+// Recorder records the articulatory state of the vocal tract during synthesis, at a fixed frame rate,
+// into a table with one row per frame, aligned with the synthesized audio in OutputData:
+// Time is the time of the frame in seconds, Sample the index of the corresponding output sample,
+// Phone the label of the phone being synthesized, Ctrl the control parameters (CurrentData) in
+// CtrlParamIdxs order, and OroCoefs and NasalCoefs the oropharynx and nasal tract scattering junction
+// coefficients -- with one coefficient per section of the Waveguide and its nasal tract if Config.NSects > 0
+type Recorder struct {
+	On          bool
+	FrameRate   float32      // frames per second at which the state is recorded
+	Phone       string       // label of the phone currently being synthesized -- set by SynthSeq, and can be set directly when synthesizing from control values
+	Table       etable.Table // the recorded frames
+	NSamples    int          // number of samples synthesized (at the internal sample rate) since the recording was reset
+	Time        float64      // time in seconds of the next sample synthesized -- accumulated per sample, as the internal sample rate can change
+	NextFrame   float64      // time in seconds of the next frame to be recorded
+	NOroCoefs   int          // number of OroCoefs per frame in the table
+	NNasalCoefs int          // number of NasalCoefs per frame in the table
+}
+
+// Defaults sets the default recorder params
+func (rc *Recorder) Defaults() {
+	rc.FrameRate = 200
+}
+
+// ConfigTable configures the table columns, with no rows, for nOroCoefs oropharynx and nNasalCoefs
+// nasal tract coefficients per frame
+func (rc *Recorder) ConfigTable(nOroCoefs, nNasalCoefs int) {
+	rc.NOroCoefs = nOroCoefs
+	rc.NNasalCoefs = nNasalCoefs
+	sch := etable.Schema{
+		{Name: "Time", Type: etensor.FLOAT64},
+		{Name: "Sample", Type: etensor.INT64},
+		{Name: "Phone", Type: etensor.STRING},
+		{Name: "Ctrl", Type: etensor.FLOAT32, CellShape: []int{NCtrlParams}, DimNames: []string{"Param"}},
+		{Name: "OroCoefs", Type: etensor.FLOAT32, CellShape: []int{nOroCoefs}, DimNames: []string{"Coef"}},
+		{Name: "NasalCoefs", Type: etensor.FLOAT32, CellShape: []int{nNasalCoefs}, DimNames: []string{"Coef"}},
+	}
+	rc.Table.SetFromSchema(sch, 0)
+}
+
+// Reset clears the recorded frames, to start a new recording aligned with the start of OutputData
+func (rc *Recorder) Reset() {
+	if rc.Table.ColIdx("Ctrl") >= 0 {
+		rc.Table.SetNumRows(0)
+	}
+	rc.NSamples = 0
+	rc.Time = 0
+	rc.NextFrame = 0
+}
+
+// Record is called for each sample synthesized by the vocal tract, and adds a frame to the
+// table when the time for the next frame has been reached
+func (rc *Recorder) Record(vt *VocalTract) {
+	t := rc.Time
+	rc.Time += 1 / float64(vt.SampleRate)
+	rc.NSamples++
+	if t < rc.NextFrame || rc.FrameRate <= 0 {
+		return
+	}
+	rc.NextFrame += 1 / float64(rc.FrameRate)
+	oroCoefs, nasalCoefs := vt.TractCoefs()
+	if rc.Table.ColIdx("Ctrl") < 0 || rc.NOroCoefs != len(oroCoefs) || rc.NNasalCoefs != len(nasalCoefs) {
+		rc.ConfigTable(len(oroCoefs), len(nasalCoefs))
+	}
+	dt := &rc.Table
+	row := dt.Rows
+	dt.AddRows(1)
+	dt.SetCellFloat("Time", row, t)
+	dt.SetCellFloat("Sample", row, math.Round(t*float64(vt.Config.OutputRate)))
+	dt.SetCellString("Phone", row, rc.Phone)
+	for i := 0; i < NCtrlParams; i++ {
+		dt.SetCellTensorFloat1D("Ctrl", row, i, float64(*vt.CurrentData.ParamVal(i)))
+	}
+	for i, c := range oroCoefs {
+		dt.SetCellTensorFloat1D("OroCoefs", row, i, float64(c))
+	}
+	for i, c := range nasalCoefs {
+		dt.SetCellTensorFloat1D("NasalCoefs", row, i, float64(c))
+	}
+}
+
+// phoneAt returns the index of the phone in the sequence being synthesized at time t in ms,
+// after Timing -- the last phone if t is beyond the end of the sequence
+func phoneAt(seq []Phone, t float32) int {
+	for i := range seq {
+		ph := &seq[i]
+		if t < ph.Onset+ph.Transition+ph.Duration {
+			return i
+		}
+	}
+	return len(seq) - 1
+}
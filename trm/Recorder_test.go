@@ -0,0 +1,83 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	for _, nSects := range []int{0, 12} {
+		vt := &VocalTract{}
+		vt.Config.Defaults()
+		vt.Config.NSects = nSects
+		if err := vt.Init(); err != nil {
+			t.Fatalf("Init error: %v", err)
+		}
+		vt.Initialize()
+		vt.Recorder.On = true
+		for rep := 0; rep < 2; rep++ { // the second synthesis resets the recording
+			if err := vt.SynthPhones("h_e_l_o", true, false); err != nil {
+				t.Fatalf("NSects %d: SynthPhones error: %v", nSects, err)
+			}
+		}
+		vt.FlushStream() // converts the samples held back by the sample rate converter
+		dt := &vt.Recorder.Table
+		dur := float64(len(vt.OutputData)) / float64(vt.Config.OutputRate)
+		if want := dur * float64(vt.Recorder.FrameRate); math.Abs(float64(dt.Rows)-want) > 2 {
+			t.Errorf("NSects %d: %d frames recorded for %v sec, want about %v", nSects, dt.Rows, dur, want)
+		}
+		nOro, nNasal := OroPharynxCoefCount, NasalTractCoefCount
+		if nSects > 0 {
+			nOro, nNasal = nSects, vt.Waveguide.NasalSects
+		}
+		if n := dt.ColByName("OroCoefs").Shapes()[1]; n != nOro {
+			t.Errorf("NSects %d: %d OroCoefs per frame, want %d", nSects, n, nOro)
+		}
+		if n := dt.ColByName("NasalCoefs").Shapes()[1]; n != nNasal {
+			t.Errorf("NSects %d: %d NasalCoefs per frame, want %d", nSects, n, nNasal)
+		}
+
+		var phones []string
+		for row := 0; row < dt.Rows; row++ {
+			tm := dt.CellFloat("Time", row)
+			if want := float64(row) / float64(vt.Recorder.FrameRate); math.Abs(tm-want) > 1e-4 {
+				t.Fatalf("NSects %d: frame %d at %v sec, want %v", nSects, row, tm, want)
+			}
+			if smp := int(dt.CellFloat("Sample", row)); smp < 0 || smp > len(vt.OutputData) {
+				t.Fatalf("NSects %d: frame %d at sample %d, beyond the %d samples", nSects, row, smp, len(vt.OutputData))
+			}
+			if ph := dt.CellString("Phone", row); len(phones) == 0 || phones[len(phones)-1] != ph {
+				phones = append(phones, ph)
+			}
+		}
+		want := []string{"h", "e", "l", "o"}
+		if len(phones) != len(want) {
+			t.Fatalf("NSects %d: phones recorded = %v, want %v", nSects, phones, want)
+		}
+		for i := range want {
+			if phones[i] != want[i] {
+				t.Errorf("NSects %d: phones recorded = %v, want %v", nSects, phones, want)
+				break
+			}
+		}
+	}
+}
+
+func TestPhoneAt(t *testing.T) {
+	seq := testSeq(testPosture("a", nil, 0, 10, 10), testPosture("b", nil, 0, 20, 20))
+	tests := []struct {
+		t    float32
+		want int
+	}{
+		{0, 0}, {19, 0}, {20, 1}, {59, 1}, {100, 1},
+	}
+	for _, tt := range tests {
+		if got := phoneAt(seq, tt.t); got != tt.want {
+			t.Errorf("phoneAt(%v) = %d, want %d", tt.t, got, tt.want)
+		}
+	}
+}
@@ -44,6 +44,12 @@ func (rf *ReflectionFilter) Init(apertureCoef float32) {
 	rf.A10 = 1.0 - math32.Abs(rf.B11)
 }
 
+// SetCoef sets the filter coefficients for the aperture coefficient, without resetting the filter
+func (rf *ReflectionFilter) SetCoef(apertureCoef float32) {
+	rf.B11 = -apertureCoef
+	rf.A10 = 1.0 - math32.Abs(rf.B11)
+}
+
 // Reset set ReflectionY to 0
 func (rf *ReflectionFilter) Reset() {
 	rf.ReflectionY = 0.0
@@ -0,0 +1,37 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReflectionFilter(t *testing.T) {
+	const a = 0.5
+	var rf ReflectionFilter
+	rf.Init(a)
+	// y[n] = (1 - a) * x[n] + a * y[n-1]
+	want := []float32{1 - a, (1 - a) * a, (1 - a) * a * a}
+	for i, w := range want {
+		in := float32(0)
+		if i == 0 {
+			in = 1
+		}
+		if got := rf.Filter(in); math.Abs(float64(got-w)) > 1e-6 {
+			t.Errorf("impulse response %d = %v, want %v", i, got, w)
+		}
+	}
+
+	// lowpass with unity gain: the response to a step goes to 1
+	rf.Reset()
+	var y float32
+	for i := 0; i < 100; i++ {
+		y = rf.Filter(1)
+	}
+	if math.Abs(float64(y-1)) > 1e-5 {
+		t.Errorf("step response after 100 samples = %v, want 1", y)
+	}
+}
@@ -0,0 +1,429 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/emer/etable/etable"
+)
+
+// This file implements a Monet-style (gnuspeech) rule system for generating the control
+// trajectories for a sequence of phones: each phone has a Posture with a target value for
+// each control parameter, and Rules matching sequences of 2 (diphone) or 3 (triphone)
+// postures specify the Profile of the transition of each parameter from one target to the next,
+// along with SpecialEvents that add transient deviations (e.g., aspiration at a stop release).
+
+/////////////////////////////////////////////////////
+//              Posture
+
+// Posture is the articulatory target for a phone, along with its timing and the
+// categories it belongs to, which are used for matching Rules
+type Posture struct {
+	Name       string
+	Cats       []string             // categories, e.g., vowel, consonant, stop, voiced
+	Targets    [NCtrlParams]float32 // target value of each control parameter, in CtrlParamIdxs order
+	Duration   float32              // duration of the steady-state portion, in ms
+	Transition float32              // duration of the transition into the posture, in ms
+}
+
+// HasCat returns true if the posture has the given category
+func (ps *Posture) HasCat(cat string) bool {
+	for _, c := range ps.Cats {
+		if c == cat {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the posture matches the expression, which is a set of alternatives
+// separated by | each of which is a set of terms separated by & that must all match --
+// a term is * (matches any posture), a posture name or a category, optionally negated by a leading !,
+// e.g., "stop&unvoiced|fricative&!voiced"
+func (ps *Posture) Matches(expr string) bool {
+	for _, alt := range strings.Split(expr, "|") {
+		match := true
+		for _, term := range strings.Split(alt, "&") {
+			term = strings.TrimSpace(term)
+			neg := strings.HasPrefix(term, "!")
+			if neg {
+				term = term[1:]
+			}
+			tm := term == "*" || term == ps.Name || ps.HasCat(term)
+			if tm == neg {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// PostureSet is the set of postures available for synthesis, by name
+type PostureSet struct {
+	Postures map[string]*Posture
+}
+
+// ConfigFromTable configures the postures from a phone table (see EnglishPhones for the format) --
+// the cats column is optional, and without it rules can only match postures by name or *
+func (pst *PostureSet) ConfigFromTable(dt *etable.Table) error {
+	pcol := dt.ColByName("phone")
+	dcol := dt.ColByName("duration")
+	tcol := dt.ColByName("transition")
+	vcol := dt.ColByName("phone_data")
+	if pcol == nil || dcol == nil || tcol == nil || vcol == nil {
+		return errors.New("trm.PostureSet.ConfigFromTable: table is not a phone table")
+	}
+	ccol := dt.ColByName("cats")
+	pst.Postures = make(map[string]*Posture, dt.Rows)
+	for r := 0; r < dt.Rows; r++ {
+		ps := &Posture{Name: pcol.StringVal1D(r)}
+		ps.Duration = float32(dcol.FloatVal1D(r))
+		ps.Transition = float32(tcol.FloatVal1D(r))
+		for i := 0; i < NCtrlParams; i++ {
+			ps.Targets[i] = float32(vcol.FloatVal1D(r*NCtrlParams + i))
+		}
+		if ccol != nil {
+			ps.Cats = strings.Fields(ccol.StringVal1D(r))
+		}
+		pst.Postures[ps.Name] = ps
+	}
+	return nil
+}
+
+// Posture returns the posture for the given phone name, using the stressed variant
+// of the phone (name followed by ') if stress is true and there is one -- nil if not found
+func (pst *PostureSet) Posture(name string, stress bool) *Posture {
+	if stress {
+		if ps, ok := pst.Postures[name+"'"]; ok {
+			return ps
+		}
+	}
+	return pst.Postures[name]
+}
+
+/////////////////////////////////////////////////////
+//              Profiles and Rules
+
+// ProfilePoint is one point of a transition profile
+type ProfilePoint struct {
+	Phase int     // which transition within the rule: 0 is into the 2nd posture, 1 into the 3rd (triphones)
+	Time  float32 // time as a proportion of the transition into the posture of this phase (0-1)
+	Value float32 // for profiles, proportion of the way from the value at the end of the previous phase (the previous target for phase 0) to the target of this phase -- for special events, value added to the parameter
+}
+
+// Profile is a named shape of the transition of a parameter between postures, as a set of points
+// that are linearly interpolated -- for a triphone rule, points in phase 1 continue from wherever
+// phase 0 ended, so a phase 0 Value less than 1 gives undershoot of the middle target
+type Profile struct {
+	Name   string
+	Points []ProfilePoint
+}
+
+// SpecialEvent is a transient deviation added to a control parameter during a rule,
+// e.g., a burst of aspiration at the release of a stop -- the value is 0 outside the points
+type SpecialEvent struct {
+	Param  CtrlParamIdxs
+	Points []ProfilePoint
+}
+
+// Rule specifies the transitions for a sequence of 2 or 3 postures
+type Rule struct {
+	Name     string
+	Match    []string            // expression for each posture in the sequence -- see Posture.Matches
+	Profiles [NCtrlParams]string // name of the profile for each control parameter -- empty uses the RuleSet.DefProfile
+	Special  []SpecialEvent      // special events added during this rule
+}
+
+// Matches returns true if the rule matches the postures of the sequence starting at index st
+func (rl *Rule) Matches(seq []Phone, st int) bool {
+	if st+len(rl.Match) > len(seq) {
+		return false
+	}
+	for i, ex := range rl.Match {
+		if !seq[st+i].Posture.Matches(ex) {
+			return false
+		}
+	}
+	return true
+}
+
+// RuleSet is an ordered set of rules with the profiles they use -- for each position in a
+// sequence of phones, the first matching rule is used, so more specific rules go first
+type RuleSet struct {
+	Rules        []*Rule
+	Profiles     map[string]*Profile
+	DefProfile   string  // profile used for parameters without a profile, and for the transition from the starting state
+	DblStressDur float32 // duration multiplier for phones with double stress (")
+}
+
+// Profile returns the profile of the given name, or the default profile if not found
+func (rs *RuleSet) Profile(name string) *Profile {
+	if pr, ok := rs.Profiles[name]; ok {
+		return pr
+	}
+	return rs.Profiles[rs.DefProfile]
+}
+
+// AddProfile adds a profile with the given points, each of which is phase, time, value
+func (rs *RuleSet) AddProfile(name string, pts ...[3]float32) *Profile {
+	pr := &Profile{Name: name}
+	for _, p := range pts {
+		pr.Points = append(pr.Points, ProfilePoint{Phase: int(p[0]), Time: p[1], Value: p[2]})
+	}
+	if rs.Profiles == nil {
+		rs.Profiles = make(map[string]*Profile)
+	}
+	rs.Profiles[name] = pr
+	return pr
+}
+
+// AddRule adds a rule with the given match expressions, with all parameters using the default profile
+func (rs *RuleSet) AddRule(name string, match ...string) *Rule {
+	rl := &Rule{Name: name, Match: match}
+	rs.Rules = append(rs.Rules, rl)
+	return rl
+}
+
+// SetRadii sets the profile of all the radii in the rule
+func (rl *Rule) SetRadii(prof string) {
+	for i := Radius2Idx; i <= Radius8Idx; i++ {
+		rl.Profiles[i] = prof
+	}
+}
+
+// Defaults sets a basic set of rules for English
+func (rs *RuleSet) Defaults() {
+	rs.Rules = nil
+	rs.Profiles = nil
+	rs.DefProfile = "SCurve"
+	rs.DblStressDur = 1.2
+	rs.AddProfile("Linear", [3]float32{0, 0, 0}, [3]float32{0, 1, 1})
+	rs.AddProfile("SCurve", [3]float32{0, 0, 0}, [3]float32{0, .25, .15}, [3]float32{0, .5, .5}, [3]float32{0, .75, .85}, [3]float32{0, 1, 1})
+	rs.AddProfile("Early", [3]float32{0, 0, 0}, [3]float32{0, .3, .8}, [3]float32{0, 1, 1})
+	rs.AddProfile("Late", [3]float32{0, 0, 0}, [3]float32{0, .6, .15}, [3]float32{0, 1, 1})
+	rs.AddProfile("Delayed", [3]float32{0, 0, 0}, [3]float32{0, .5, 0}, [3]float32{0, 1, 1})
+	rs.AddProfile("Undershoot", [3]float32{0, 0, 0}, [3]float32{0, .5, .5}, [3]float32{0, 1, .85}, [3]float32{1, 0, 0}, [3]float32{1, .5, .5}, [3]float32{1, 1, 1})
+
+	rl := rs.AddRule("UnstressedVowel", "consonant", "vowel&!stressed", "consonant")
+	rl.SetRadii("Undershoot")
+
+	rl = rs.AddRule("UnvoicedStopRelease", "stop&unvoiced|affricate&unvoiced", "vowel|approximant")
+	rl.SetRadii("Early")
+	rl.Profiles[GlotVolIdx] = "Delayed"
+	rl.Special = append(rl.Special, SpecialEvent{Param: AspVolIdx, Points: []ProfilePoint{{0, 0, 0}, {0, .15, 10}, {0, .5, 0}}})
+
+	rl = rs.AddRule("VoicedStopRelease", "stop&voiced|affricate&voiced", "vowel|approximant")
+	rl.SetRadii("Early")
+
+	rl = rs.AddRule("StopClosure", "vowel|approximant", "stop|affricate")
+	rl.SetRadii("Late")
+	rl.Profiles[GlotVolIdx] = "Late"
+
+	rl = rs.AddRule("NasalOnset", "vowel|approximant", "nasal")
+	rl.Profiles[VelumIdx] = "Early"
+	rl.SetRadii("Late")
+
+	rl = rs.AddRule("NasalRelease", "nasal", "vowel|approximant")
+	rl.Profiles[VelumIdx] = "Late"
+	rl.SetRadii("Early")
+
+	rs.AddRule("Default", "*", "*")
+}
+
+/////////////////////////////////////////////////////
+//              Trajectory
+
+// Phone is one phone in a sequence to be synthesized
+type Phone struct {
+	Posture    *Posture
+	Stress     bool    // stressed, marked by '
+	DblStress  bool    // double stressed, marked by "
+	SylEnd     bool    // last phone of a syllable
+	GroupEnd   bool    // last phone of a tone group -- see Prosody
+	Duration   float32 // duration of the steady-state portion in ms -- from the posture, adjusted for stress
+	Transition float32 // duration of the transition into the phone in ms
+	Onset      float32 // start of the transition into the phone in ms from the start of the sequence -- set by Timing
+}
+
+// Timing sets the Onset of each phone in the sequence, returning the total duration in ms
+func Timing(seq []Phone) float32 {
+	t := float32(0)
+	for i := range seq {
+		seq[i].Onset = t
+		t += seq[i].Transition + seq[i].Duration
+	}
+	return t
+}
+
+// breakPt is a point in time with a parameter value
+type breakPt struct {
+	time, val float32
+}
+
+// interp returns the value at time t of the piecewise linear function through the points,
+// which is held at the first and last values outside of the points
+func interp(pts []breakPt, t float32) float32 {
+	n := len(pts)
+	if n == 0 {
+		return 0
+	}
+	if t <= pts[0].time {
+		return pts[0].val
+	}
+	i := sort.Search(n, func(i int) bool { return pts[i].time > t })
+	if i == n {
+		return pts[n-1].val
+	}
+	p0 := pts[i-1]
+	p1 := pts[i]
+	if p1.time <= p0.time {
+		return p1.val
+	}
+	return p0.val + (p1.val-p0.val)*(t-p0.time)/(p1.time-p0.time)
+}
+
+// phaseTime returns the time in ms of a profile point for the rule starting at index st
+func phaseTime(seq []Phone, st int, pt ProfilePoint) float32 {
+	ph := &seq[st+1+pt.Phase]
+	return ph.Onset + pt.Time*ph.Transition
+}
+
+// Trajectory generates the control parameters for the sequence, one for each stepMs from the start
+// of the sequence, with each value for the end of its step -- start is the state at the start
+// of the sequence, from which the transition into the first phone uses the DefProfile.
+// Timing must have been called on the sequence
+func (rs *RuleSet) Trajectory(seq []Phone, start *VocalTractCtrl, stepMs float32) ([]VocalTractCtrl, error) {
+	if len(seq) == 0 {
+		return nil, nil
+	}
+	if stepMs <= 0 {
+		return nil, fmt.Errorf("trm.RuleSet.Trajectory: step size must be positive: %v", stepMs)
+	}
+	for i := range seq {
+		if seq[i].Posture == nil {
+			return nil, fmt.Errorf("trm.RuleSet.Trajectory: phone %d has no posture", i)
+		}
+	}
+	if len(rs.Rules) == 0 {
+		rs.Defaults()
+	}
+
+	var pts [NCtrlParams][]breakPt
+	var spcs [][]breakPt
+	var spcPars []CtrlParamIdxs
+
+	// transition from the start state into the first phone
+	def := rs.Profile(rs.DefProfile)
+	for p := 0; p < NCtrlParams; p++ {
+		st := *start.ParamVal(p)
+		tg := seq[0].Posture.Targets[p]
+		for _, pt := range def.Points {
+			if pt.Phase != 0 {
+				continue
+			}
+			t := seq[0].Onset + pt.Time*seq[0].Transition
+			pts[p] = append(pts[p], breakPt{t, st + pt.Value*(tg-st)})
+		}
+	}
+
+	for st := 0; st < len(seq)-1; {
+		var rl *Rule
+		for _, r := range rs.Rules {
+			if len(r.Match) >= 2 && r.Matches(seq, st) {
+				rl = r
+				break
+			}
+		}
+		if rl == nil { // no rule: use the default profile for a diphone
+			rl = &Rule{Name: "Default", Match: []string{"*", "*"}}
+		}
+		nph := len(rl.Match) - 1
+		for p := 0; p < NCtrlParams; p++ {
+			pr := rs.Profile(rl.Profiles[p])
+			from := seq[st].Posture.Targets[p]
+			for ph := 0; ph < nph; ph++ {
+				ppts := pr.Points
+				if !profHasPhase(pr, ph) { // diphone profiles apply to each phase
+					ppts = shiftPhase(pr.Points, ph)
+				}
+				to := seq[st+ph+1].Posture.Targets[p]
+				for _, pt := range ppts {
+					if pt.Phase != ph {
+						continue
+					}
+					pts[p] = append(pts[p], breakPt{phaseTime(seq, st, pt), from + pt.Value*(to-from)})
+				}
+				from = pts[p][len(pts[p])-1].val // next phase continues from where this one ended
+			}
+			// rule must end at the last target, so the next rule starts from there
+			lst := seq[st+nph]
+			pts[p] = append(pts[p], breakPt{lst.Onset + lst.Transition, lst.Posture.Targets[p]})
+		}
+		for _, se := range rl.Special {
+			var sp []breakPt
+			for _, pt := range se.Points {
+				if pt.Phase < nph {
+					sp = append(sp, breakPt{phaseTime(seq, st, pt), pt.Value})
+				}
+			}
+			if len(sp) > 0 {
+				spcs = append(spcs, sp)
+				spcPars = append(spcPars, se.Param)
+			}
+		}
+		st += nph
+	}
+	for p := range pts {
+		sort.SliceStable(pts[p], func(i, j int) bool { return pts[p][i].time < pts[p][j].time })
+	}
+
+	tot := seq[len(seq)-1].Onset + seq[len(seq)-1].Transition + seq[len(seq)-1].Duration
+	nfr := int(math.Ceil(float64(tot / stepMs)))
+	traj := make([]VocalTractCtrl, nfr)
+	for f := range traj {
+		t := float32(f+1) * stepMs
+		for p := 0; p < NCtrlParams; p++ {
+			*traj[f].ParamVal(p) = interp(pts[p], t)
+		}
+		for si, sp := range spcs {
+			if t < sp[0].time || t > sp[len(sp)-1].time {
+				continue
+			}
+			*traj[f].ParamVal(int(spcPars[si])) += interp(sp, t)
+		}
+	}
+	return traj, nil
+}
+
+// profHasPhase returns true if the profile has points for the given phase
+func profHasPhase(pr *Profile, ph int) bool {
+	for _, pt := range pr.Points {
+		if pt.Phase == ph {
+			return true
+		}
+	}
+	return false
+}
+
+// shiftPhase returns the phase 0 points of a diphone profile moved to the given phase
+func shiftPhase(pts []ProfilePoint, ph int) []ProfilePoint {
+	var sp []ProfilePoint
+	for _, pt := range pts {
+		if pt.Phase == 0 {
+			pt.Phase = ph
+			sp = append(sp, pt)
+		}
+	}
+	return sp
+}
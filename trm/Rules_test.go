@@ -0,0 +1,162 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+// testPosture returns a posture with all targets set to val
+func testPosture(name string, cats []string, val, trans, dur float32) *Posture {
+	ps := &Posture{Name: name, Cats: cats, Transition: trans, Duration: dur}
+	for i := range ps.Targets {
+		ps.Targets[i] = val
+	}
+	return ps
+}
+
+// testSeq returns a phone sequence of the postures, with their timing set
+func testSeq(pss ...*Posture) []Phone {
+	seq := make([]Phone, len(pss))
+	for i, ps := range pss {
+		seq[i] = Phone{Posture: ps, Duration: ps.Duration, Transition: ps.Transition}
+	}
+	Timing(seq)
+	return seq
+}
+
+func TestPostureMatches(t *testing.T) {
+	ps := &Posture{Name: "t", Cats: []string{"consonant", "stop", "unvoiced"}}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"*", true},
+		{"t", true},
+		{"d", false},
+		{"stop", true},
+		{"stop&unvoiced", true},
+		{"stop&voiced", false},
+		{"stop&!voiced", true},
+		{"!stop", false},
+		{"vowel|stop&unvoiced", true},
+		{"vowel|nasal", false},
+		{" stop & unvoiced ", true},
+	}
+	for _, tt := range tests {
+		if got := ps.Matches(tt.expr); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestTrajectoryLinear(t *testing.T) {
+	var rs RuleSet
+	rs.DefProfile = "Linear"
+	rs.AddProfile("Linear", [3]float32{0, 0, 0}, [3]float32{0, 1, 1})
+	rs.AddRule("Default", "*", "*")
+	seq := testSeq(testPosture("a", nil, 0, 10, 10), testPosture("b", nil, 10, 20, 20))
+	var start VocalTractCtrl
+	traj, err := rs.Trajectory(seq, &start, 5)
+	if err != nil {
+		t.Fatalf("Trajectory error: %v", err)
+	}
+	if len(traj) != 12 {
+		t.Fatalf("trajectory of %d steps, want 12", len(traj))
+	}
+	for f := range traj {
+		tm := float32(f+1) * 5
+		var want float32
+		switch {
+		case tm >= 40:
+			want = 10
+		case tm > 20:
+			want = (tm - 20) / 2
+		}
+		for p := 0; p < NCtrlParams; p++ {
+			if got := *traj[f].ParamVal(p); math.Abs(float64(got-want)) > 1e-5 {
+				t.Errorf("param %d at %v ms = %v, want %v", p, tm, got, want)
+			}
+		}
+	}
+}
+
+func TestTrajectorySpecialEvent(t *testing.T) {
+	var rs RuleSet
+	rs.Defaults()
+	seq := testSeq(testPosture("t", []string{"consonant", "stop", "unvoiced"}, 0, 10, 10),
+		testPosture("a", []string{"vowel", "voiced"}, 0, 20, 20))
+	var start VocalTractCtrl
+	traj, err := rs.Trajectory(seq, &start, 1)
+	if err != nil {
+		t.Fatalf("Trajectory error: %v", err)
+	}
+	// the aspiration burst of the UnvoicedStopRelease rule peaks at 10 at .15 of the 20 ms
+	// transition into the vowel, which starts at 20 ms, and ends half way
+	tests := []struct {
+		ms   int
+		want float32
+	}{
+		{19, 0},
+		{23, 10},
+		{25, 10 * 5.0 / 7.0},
+		{31, 0},
+	}
+	for _, tt := range tests {
+		if got := traj[tt.ms-1].AspVol; math.Abs(float64(got-tt.want)) > 1e-4 {
+			t.Errorf("AspVol at %d ms = %v, want %v", tt.ms, got, tt.want)
+		}
+	}
+}
+
+func TestTrajectoryUndershoot(t *testing.T) {
+	cons := []string{"consonant"}
+	for _, stressed := range []bool{false, true} {
+		vcats := []string{"vowel"}
+		if stressed {
+			vcats = append(vcats, "stressed")
+		}
+		var rs RuleSet
+		rs.Defaults()
+		seq := testSeq(testPosture("k", cons, 0, 20, 20), testPosture("a", vcats, 10, 20, 20), testPosture("p", cons, 0, 20, 20))
+		var start VocalTractCtrl
+		traj, err := rs.Trajectory(seq, &start, 1)
+		if err != nil {
+			t.Fatalf("Trajectory error: %v", err)
+		}
+		var max float32
+		for _, c := range traj {
+			if c.Radii[3] > max {
+				max = c.Radii[3]
+			}
+		}
+		// the UnstressedVowel rule only reaches .85 of the radii of the vowel
+		want := float32(8.5)
+		if stressed {
+			want = 10
+		}
+		if math.Abs(float64(max-want)) > 1e-4 {
+			t.Errorf("stressed %v: max radius = %v, want %v", stressed, max, want)
+		}
+	}
+}
+
+func TestTrajectoryErrors(t *testing.T) {
+	var rs RuleSet
+	rs.Defaults()
+	var start VocalTractCtrl
+	if traj, err := rs.Trajectory(nil, &start, 5); traj != nil || err != nil {
+		t.Errorf("empty sequence: Trajectory = %v, %v, want nil, nil", traj, err)
+	}
+	seq := testSeq(testPosture("a", nil, 0, 10, 10))
+	if _, err := rs.Trajectory(seq, &start, 0); err == nil {
+		t.Errorf("zero step size: no error")
+	}
+	seq = append(seq, Phone{Duration: 10})
+	if _, err := rs.Trajectory(seq, &start, 5); err == nil {
+		t.Errorf("phone without posture: no error")
+	}
+}
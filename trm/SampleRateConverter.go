@@ -55,33 +55,37 @@ const NMask uint32 = 0xFFFF0000
 const LMask uint32 = 0x0000FF00
 const MMask uint32 = 0x000000FF
 const FractionMask uint32 = 0x0000FFFF
-const BufferSize = 1024  // ring buffer size
-const OutputRate = 44100 // output sample rate (22.05, 44.1 KHz)
+const BufferSize = 1024         // ring buffer size
+const DefaultOutputRate = 44100 // default output sample rate (16, 22.05, 44.1, 48 KHz are typical)
 
 type SampleRateConverter struct {
 	SampleRateRatio       float32
-	FillPtr               uint32
-	EmptyPtr              uint32
-	PadSize               uint32
-	FillSize              uint32
-	TimeRegisterIncrement uint
-	FilterIncrement       uint
-	PhaseIncrement        uint
+	FillPtr               int
+	EmptyPtr              int
+	PadSize               int
+	FillSize              int
+	TimeRegisterIncrement uint32
+	FilterIncrement       uint32
+	PhaseIncrement        uint32
 	TimeRegister          uint32
-	FillCounter           uint32
+	FillCounter           int
 	MaximumSampleValue    float32
 	NumberSamples         int64
 
 	H      [FilterLength]float32
 	DeltaH [FilterLength]float32
 	Buffer [BufferSize]float32
-	// ToDo - is this correct?
-	OutputData []float32
-	//std::vector<float>& outputData_;
+
+	OutputData *[]float32 // converted samples are appended to this slice, which is owned by the caller, unless there is a Sink
+
+	Sink      Sink      // if set, converted samples are delivered to this function in blocks, instead of OutputData -- see SetSink
+	BlockSize int       // number of samples in each block delivered to the Sink -- 0 delivers the samples as they are converted
+	Block     []float32 // current block of samples for the Sink
 }
 
+// Init initializes the conversion from sampleRate to outputRate -- converted samples are appended to outputData
 func (src *SampleRateConverter) Init(sampleRate int, outputRate int, outputData *[]float32) {
-	src.OutputData = append(src.OutputData, *outputData...)
+	src.OutputData = outputData
 	src.InitConversion(sampleRate, float32(outputRate))
 }
 
@@ -92,33 +96,49 @@ func (src *SampleRateConverter) Reset() {
 	src.FillCounter = 0
 	src.MaximumSampleValue = 0.0
 	src.NumberSamples = 0
+	src.Block = src.Block[:0]
 	src.InitBuffer()
 }
 
 // InitConversion initializes all the sample rate conversion functions
 func (src *SampleRateConverter) InitConversion(sampleRate int, outputRate float32) {
 	src.InitFilter() // initialize filter impulse response
+	src.SetRatio(sampleRate, outputRate)
+	src.InitBuffer() // initialize the ring buffer
+}
 
+// SetRatio sets the conversion ratio, time increments and padding for conversion from sampleRate to outputRate
+func (src *SampleRateConverter) SetRatio(sampleRate int, outputRate float32) {
 	src.SampleRateRatio = outputRate / float32(sampleRate)
 
 	// math32 missing Round
-	src.TimeRegisterIncrement = uint(math.Round(math.Pow(2.0, float64(FractionBits)) / float64(src.SampleRateRatio)))
+	src.TimeRegisterIncrement = uint32(math.Round(math.Pow(2.0, float64(FractionBits)) / float64(src.SampleRateRatio)))
 
 	roundedSampleRateRatio := math32.Pow(2.0, FractionBits) / float32(src.TimeRegisterIncrement)
 
 	if src.SampleRateRatio >= 1.0 {
 		src.FilterIncrement = LRange
 	} else {
-		src.PhaseIncrement = uint(math.Round(float64(src.SampleRateRatio) * FractionRange))
+		src.PhaseIncrement = uint32(math.Round(float64(src.SampleRateRatio) * FractionRange))
 	}
 
 	if src.SampleRateRatio >= 1.0 {
 		src.PadSize = ZeroCrossings
 	} else {
-		src.PadSize = uint32(float32(ZeroCrossings)/roundedSampleRateRatio) + 1
+		src.PadSize = int(float32(ZeroCrossings)/roundedSampleRateRatio) + 1
 	}
+}
 
-	src.InitBuffer() // initialize the ring buffer
+// SetSampleRate changes the input sample rate while converting, e.g., as the length of the vocal tract
+// changes, keeping the samples in the ring buffer so the output continues at the same output rate --
+// the samples buffered at the previous rate are converted first
+func (src *SampleRateConverter) SetSampleRate(sampleRate int, outputRate float32) {
+	if outputRate/float32(sampleRate) == src.SampleRateRatio {
+		return
+	}
+	src.Drain()
+	src.SetRatio(sampleRate, outputRate)
+	src.FillSize = BufferSize - (2 * src.PadSize)
 }
 
 // IZero Returns the value for the modified Bessel function of the first kind, order 0, as a float
@@ -135,7 +155,7 @@ func (src *SampleRateConverter) IZero(x float32) float32 {
 		temp *= temp
 		u *= temp
 		sum += u
-		if u >= IZeroEpsilon*sum {
+		if u < IZeroEpsilon*sum {
 			break
 		}
 	}
@@ -166,8 +186,8 @@ func (src *SampleRateConverter) InitFilter() {
 	// apply a kaiser window to the impulse response
 	iBeta := 1.0 / src.IZero(Beta)
 	for i := 0; i < FilterLength; i++ {
-		temp := float32(i / FilterLength)
-		src.H[i] = src.IZero(math32.Sqrt(float32(1.0)-(temp*temp))) * iBeta
+		temp := float32(i) / float32(FilterLength)
+		src.H[i] *= src.IZero(Beta*math32.Sqrt(float32(1.0)-(temp*temp))) * iBeta
 	}
 
 	for i := 0; i < FilterLimit; i++ {
@@ -191,6 +211,11 @@ func (src *SampleRateConverter) DataFill(data float32) {
 // DataEmpty converts available portion of the input signal to the new sampling rate,
 // and outputs the samples to the sound struct.
 func (src *SampleRateConverter) DataEmpty() {
+	// nothing to convert until there are more than PadSize samples past the empty pointer,
+	// which may not be the case just after the PadSize has increased in SetSampleRate
+	if (src.FillPtr-src.EmptyPtr+BufferSize)%BufferSize <= src.PadSize {
+		return
+	}
 	endPtr := src.FillPtr - src.PadSize
 
 	if endPtr < 0 {
@@ -207,56 +232,117 @@ func (src *SampleRateConverter) DataEmpty() {
 
 			// compute the left side of the filter convolution
 			index := src.EmptyPtr
-			for fidx := LValue(src.TimeRegister); fidx < FilterLength; fidx += uint32(src.FilterIncrement) {
+			for fidx := LValue(src.TimeRegister); fidx < FilterLength; fidx += src.FilterIncrement {
+				output += src.Buffer[index] * (src.H[fidx] + (src.DeltaH[fidx] * interpolation))
 				SrDecrement(&index, BufferSize)
-				output += (src.Buffer[index]*src.H[fidx] + (src.DeltaH[fidx] * interpolation))
 			}
 
 			// adjust values for right side calculation
-			src.TimeRegister ^= src.TimeRegister // inverse of each bit
+			src.TimeRegister = ^src.TimeRegister // inverse of each bit
 			interpolation = float32(MValue(src.TimeRegister)) / float32(MRange)
 
 			// compute the right side of the filter convolution
 			index = src.EmptyPtr
 			SrIncrement(&index, BufferSize)
-			for fidx := LValue(src.TimeRegister); fidx < FilterLength; fidx += uint32(src.FilterIncrement) {
-				SrDecrement(&index, BufferSize)
-				output += (src.Buffer[index]*src.H[fidx] + (src.DeltaH[fidx] * interpolation))
+			for fidx := LValue(src.TimeRegister); fidx < FilterLength; fidx += src.FilterIncrement {
+				output += src.Buffer[index] * (src.H[fidx] + (src.DeltaH[fidx] * interpolation))
+				SrIncrement(&index, BufferSize)
 			}
 
-			// record maximum sample value
-			absoluteSampleValue := math32.Abs(float32(output))
-			if absoluteSampleValue > src.MaximumSampleValue {
-				src.MaximumSampleValue = absoluteSampleValue
-			}
+			// change time register back to original form
+			src.TimeRegister = ^src.TimeRegister
 
-			src.NumberSamples += 1
+			src.SaveSample(output)
+			src.IncrementTime(&endPtr)
+		}
+	} else { // downsample loop
+		for src.EmptyPtr < endPtr {
+			output := float32(0.0)
 
-			// save the sample
-			src.OutputData = append(src.OutputData, output)
+			// compute p prime
+			phaseIdx := uint32(math.Round(float64(FractionValue(src.TimeRegister)) * float64(src.SampleRateRatio)))
 
-			// change time register back to original form
-			src.TimeRegister ^= src.TimeRegister
+			// compute the left side of the filter convolution
+			index := src.EmptyPtr
+			for impulseIdx := phaseIdx >> MBits; impulseIdx < FilterLength; impulseIdx = phaseIdx >> MBits {
+				impulse := src.H[impulseIdx] + (src.DeltaH[impulseIdx] * (float32(MValue(phaseIdx)) / float32(MRange)))
+				output += src.Buffer[index] * impulse
+				SrDecrement(&index, BufferSize)
+				phaseIdx += src.PhaseIncrement
+			}
 
-			// increment the empty pointer, adjusting it and end pointer
-			src.EmptyPtr += NValue(src.TimeRegister)
-			if src.EmptyPtr >= BufferSize {
-				src.EmptyPtr -= BufferSize
-				endPtr -= BufferSize
+			// compute p prime, adjusted for right side
+			phaseIdx = uint32(math.Round(float64(FractionValue(^src.TimeRegister)) * float64(src.SampleRateRatio)))
+
+			// compute the right side of the filter convolution
+			index = src.EmptyPtr
+			SrIncrement(&index, BufferSize)
+			for impulseIdx := phaseIdx >> MBits; impulseIdx < FilterLength; impulseIdx = phaseIdx >> MBits {
+				impulse := src.H[impulseIdx] + (src.DeltaH[impulseIdx] * (float32(MValue(phaseIdx)) / float32(MRange)))
+				output += src.Buffer[index] * impulse
+				SrIncrement(&index, BufferSize)
+				phaseIdx += src.PhaseIncrement
 			}
 
-			// clear n part of time register
-			src.TimeRegister &= ^NMask
+			// the filter taps are denser by 1 / ratio when downsampling -- rescale to unity gain
+			src.SaveSample(output * src.SampleRateRatio)
+			src.IncrementTime(&endPtr)
+		}
+	}
+	if src.BlockSize <= 0 {
+		src.FlushSink()
+	}
+}
+
+// Drain converts all the samples in the ring buffer that can be converted now, without waiting for the
+// buffer to fill, e.g., for low latency -- only the last PadSize samples are held back
+func (src *SampleRateConverter) Drain() {
+	src.DataEmpty()
+	src.FillCounter = 0
+}
+
+// SaveSample records the maximum sample value and appends the converted sample to the output,
+// or to the current block for the Sink
+func (src *SampleRateConverter) SaveSample(output float32) {
+	absoluteSampleValue := math32.Abs(output)
+	if absoluteSampleValue > src.MaximumSampleValue {
+		src.MaximumSampleValue = absoluteSampleValue
+	}
+	src.NumberSamples += 1
+	if src.Sink != nil {
+		src.Block = append(src.Block, output)
+		if src.BlockSize > 0 && len(src.Block) >= src.BlockSize {
+			src.Sink(src.Block)
+			src.Block = src.Block[:0]
 		}
+		return
+	}
+	if src.OutputData != nil {
+		*src.OutputData = append(*src.OutputData, output)
 	}
 }
 
+// IncrementTime increments the time register and the empty pointer, adjusting the empty and end pointers
+// when wrapping around the ring buffer
+func (src *SampleRateConverter) IncrementTime(endPtr *int) {
+	src.TimeRegister += src.TimeRegisterIncrement
+
+	src.EmptyPtr += int(NValue(src.TimeRegister))
+	if src.EmptyPtr >= BufferSize {
+		src.EmptyPtr -= BufferSize
+		*endPtr -= BufferSize
+	}
+
+	// clear n part of time register
+	src.TimeRegister &= ^NMask
+}
+
 func (src *SampleRateConverter) MaxSampleVal() float32 {
 	return src.MaximumSampleValue
 }
 
 // SrIncrement increments the buffer position keeping it within the range 0 to (modulus - 1)
-func SrIncrement(pos *uint32, modulus uint32) {
+func SrIncrement(pos *int, modulus int) {
 	*pos += 1
 	if *pos >= modulus {
 		*pos -= modulus
@@ -264,7 +350,7 @@ func SrIncrement(pos *uint32, modulus uint32) {
 }
 
 // SrDecrement decrements the buffer position keeping it within the range 0 to (modulus - 1)
-func SrDecrement(pos *uint32, modulus uint32) {
+func SrDecrement(pos *int, modulus int) {
 	*pos -= 1
 	if *pos < 0 {
 		*pos += modulus
@@ -273,7 +359,7 @@ func SrDecrement(pos *uint32, modulus uint32) {
 
 // FlushBuffer pads the buffer with zero samples, and flushes it by converting the remaining samples
 func (src *SampleRateConverter) FlushBuffer() {
-	for i := 0; i < int(src.PadSize*2); i++ {
+	for i := 0; i < src.PadSize*2; i++ {
 		src.DataFill(0.0)
 	}
 	src.DataEmpty()
@@ -0,0 +1,64 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSampleRateConverter(t *testing.T) {
+	const freq, amp = 500, 0.8
+	rates := []struct{ in, out int }{
+		{20000, 44100},
+		{44100, 16000},
+		{30000, 30000},
+	}
+	for _, r := range rates {
+		var out []float32
+		var src SampleRateConverter
+		src.Init(r.in, r.out, &out)
+		src.Reset()
+		for i := 0; i < r.in; i++ { // 1 sec
+			src.DataFill(float32(amp * math.Sin(2*math.Pi*freq*float64(i)/float64(r.in))))
+		}
+		src.FlushBuffer()
+		pad := 2 * src.PadSize * r.out / r.in
+		if d := len(out) - r.out; d < 0 || d > pad+2 {
+			t.Errorf("%d to %d: %d output samples, want %d plus up to %d flushed", r.in, r.out, len(out), r.out, pad)
+		}
+		if m := src.MaxSampleVal(); math.Abs(float64(m)-amp) > 0.02 {
+			t.Errorf("%d to %d: MaxSampleVal = %v, want about %v", r.in, r.out, m, amp)
+		}
+
+		// fit a sine at the output rate to the middle .5 sec of the output, which is a whole number of
+		// periods -- the fit gives the amplitude and the filter delay as the phase
+		st, ed := r.out/4, 3*r.out/4
+		sn, cs := 0.0, 0.0
+		for i := st; i < ed; i++ {
+			ph := 2 * math.Pi * freq * float64(i) / float64(r.out)
+			sn += float64(out[i]) * math.Sin(ph)
+			cs += float64(out[i]) * math.Cos(ph)
+		}
+		sn *= 2 / float64(ed-st)
+		cs *= 2 / float64(ed-st)
+		if a := math.Hypot(sn, cs); math.Abs(a-amp) > 0.01 {
+			t.Errorf("%d to %d: amplitude of the %d Hz sine = %v, want %v", r.in, r.out, freq, a, amp)
+		}
+		mx := 0.0
+		for i := st; i < ed; i++ {
+			ph := 2 * math.Pi * freq * float64(i) / float64(r.out)
+			mx = math.Max(mx, math.Abs(float64(out[i])-sn*math.Sin(ph)-cs*math.Cos(ph)))
+		}
+		if mx > 0.02 {
+			t.Errorf("%d to %d: max difference from a %d Hz sine = %v, want < 0.02", r.in, r.out, freq, mx)
+		}
+
+		src.Reset()
+		if m := src.MaxSampleVal(); m != 0 {
+			t.Errorf("%d to %d: MaxSampleVal after Reset = %v, want 0", r.in, r.out, m)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"github.com/chewxy/math32"
+	"github.com/go-audio/audio"
+)
+
+// Spatializer places a sound source at an azimuth around the head, by applying the interaural
+// time difference (ITD) of a spherical head (Woodworth's formula) and a broadband interaural
+// level difference (ILD) to the ear away from the source
+type Spatializer struct {
+	On         bool
+	Azimuth    float32 // direction of the source in degrees, from -90 (left) through 0 (ahead) to 90 (right)
+	HeadRadius float32 // radius of the head in cm, which determines the ITD
+	MaxILD     float32 // ILD in dB for a source at 90 degrees, which is scaled by the sine of the azimuth
+}
+
+// Defaults sets the default spatializer params, for an adult head
+func (sp *Spatializer) Defaults() {
+	sp.Azimuth = 0
+	sp.HeadRadius = 8.75
+	sp.MaxILD = 10
+}
+
+// azimuth returns the azimuth in radians, limited to +/- 90 degrees
+func (sp *Spatializer) azimuth() float32 {
+	az := sp.Azimuth
+	if az > 90 {
+		az = 90
+	} else if az < -90 {
+		az = -90
+	}
+	return az * math32.Pi / 180
+}
+
+// ITD returns the interaural time difference in seconds -- the delay of the ear away from the source
+func (sp *Spatializer) ITD() float32 {
+	th := math32.Abs(sp.azimuth())
+	c := SpeedOfSound(20) * 100 // cm / sec, in air at room temperature
+	return sp.HeadRadius / c * (th + math32.Sin(th))
+}
+
+// ILD returns the interaural level difference in dB -- the attenuation of the ear away from the source
+func (sp *Spatializer) ILD() float32 {
+	return sp.MaxILD * math32.Abs(math32.Sin(sp.azimuth()))
+}
+
+// Spatialize returns the left and right channels with the ITD and ILD applied to the ear away from the
+// source, at the given sample rate -- the channels are extended by the delay of the far ear
+func (sp *Spatializer) Spatialize(left, right []float32, rate int) ([]float32, []float32) {
+	dly := sp.ITD() * float32(rate)
+	gain := math32.Pow(10, -sp.ILD()/20)
+	nd := int(math32.Ceil(dly))
+	n := len(left)
+	if len(right) > n {
+		n = len(right)
+	}
+	nl := make([]float32, n+nd)
+	nr := make([]float32, n+nd)
+	copy(nl, left)
+	copy(nr, right)
+	far := nl
+	if sp.Azimuth < 0 {
+		far = nr
+	}
+	// delay the far ear by dly samples, interpolating linearly, working backward in place
+	di := int(dly)
+	fr := dly - float32(di)
+	for i := len(far) - 1; i >= 0; i-- {
+		var a, b float32
+		if j := i - di; j >= 0 {
+			a = far[j]
+		}
+		if j := i - di - 1; j >= 0 {
+			b = far[j]
+		}
+		far[i] = gain * ((1-fr)*a + fr*b)
+	}
+	return nl, nr
+}
+
+// Stereo returns the output as left and right channels, scaled to a maximum of OutputScale at full
+// Volume, and panned according to Balance, from -1 (left) to 1 (right) -- the Spatializer is applied if On
+func (vt *VocalTract) Stereo() (left, right []float32) {
+	var ls, rs float32
+	vt.CalculateStereoScale(&ls, &rs)
+	left = make([]float32, len(vt.OutputData))
+	right = make([]float32, len(vt.OutputData))
+	for i, v := range vt.OutputData {
+		left[i] = v * ls
+		right[i] = v * rs
+	}
+	if vt.Spatializer.On {
+		left, right = vt.Spatializer.Spatialize(left, right, vt.Config.OutputRate)
+	}
+	return
+}
+
+// StereoBuf returns the output from Stereo as an interleaved 2 channel, 16 bit buffer, e.g., to be written to a wav file
+func (vt *VocalTract) StereoBuf() *audio.IntBuffer {
+	left, right := vt.Stereo()
+	data := make([]int, 2*len(left))
+	for i := range left {
+		data[2*i] = pcm16(left[i])
+		data[2*i+1] = pcm16(right[i])
+	}
+	format := &audio.Format{
+		NumChannels: 2,
+		SampleRate:  vt.Config.OutputRate,
+	}
+	return &audio.IntBuffer{Data: data, Format: format, SourceBitDepth: 16}
+}
+
+// pcm16 converts a sample in the range -1 to 1 to a 16 bit integer value, clipping beyond the range
+func pcm16(v float32) int {
+	if v > 1 {
+		v = 1
+	} else if v < -1 {
+		v = -1
+	}
+	return int(v * 32767)
+}
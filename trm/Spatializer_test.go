@@ -0,0 +1,89 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestITD(t *testing.T) {
+	var sp Spatializer
+	sp.Defaults()
+	if itd := sp.ITD(); itd != 0 {
+		t.Errorf("ITD ahead = %v, want 0", itd)
+	}
+	sp.Azimuth = 90
+	itd90 := sp.ITD()
+	// Woodworth: r / c * (pi/2 + 1), about 0.66 ms for an adult head
+	if math.Abs(float64(itd90)-0.000656) > 0.00001 {
+		t.Errorf("ITD at 90 degrees = %v sec, want about 0.000656", itd90)
+	}
+	sp.Azimuth = 120
+	if itd := sp.ITD(); itd != itd90 {
+		t.Errorf("ITD at 120 degrees = %v, want %v as at 90", itd, itd90)
+	}
+	sp.Azimuth = 30
+	itd30 := sp.ITD()
+	sp.Azimuth = -30
+	if itd := sp.ITD(); itd != itd30 || !(itd30 > 0 && itd30 < itd90) {
+		t.Errorf("ITD at -30 degrees = %v, at 30 = %v, want the same, between 0 and %v", itd, itd30, itd90)
+	}
+	if ild := sp.ILD(); math.Abs(float64(ild)-5) > 1e-4 {
+		t.Errorf("ILD at -30 degrees = %v, want 5", ild)
+	}
+}
+
+// centroid returns the sum and the centroid in samples of the signal
+func centroid(x []float32) (sum, ctr float64) {
+	for i, v := range x {
+		sum += float64(v)
+		ctr += float64(i) * float64(v)
+	}
+	return sum, ctr / sum
+}
+
+func TestSpatialize(t *testing.T) {
+	const rate, at = 44100, 10
+	for _, az := range []float32{60, -60} {
+		var sp Spatializer
+		sp.Defaults()
+		sp.Azimuth = az
+		left := make([]float32, 100)
+		right := make([]float32, 100)
+		left[at] = 1
+		right[at] = 1
+		nl, nr := sp.Spatialize(left, right, rate)
+		dly := float64(sp.ITD() * rate)
+		if len(nl) != 100+int(math.Ceil(dly)) || len(nr) != len(nl) {
+			t.Errorf("azimuth %v: %d and %d samples, want %d", az, len(nl), len(nr), 100+int(math.Ceil(dly)))
+		}
+		near, far := nr, nl // a source on the right is delayed and attenuated in the left ear
+		if az < 0 {
+			near, far = nl, nr
+		}
+		if sum, ctr := centroid(near); sum != 1 || ctr != at {
+			t.Errorf("azimuth %v: near ear impulse of %v at %v, want 1 at %v", az, sum, ctr, at)
+		}
+		gain := math.Pow(10, -float64(sp.ILD())/20)
+		if sum, ctr := centroid(far); math.Abs(sum-gain) > 1e-4 || math.Abs(ctr-(at+dly)) > 1e-3 {
+			t.Errorf("azimuth %v: far ear impulse of %v at %v, want %v at %v", az, sum, ctr, gain, at+dly)
+		}
+	}
+}
+
+func TestPCM16(t *testing.T) {
+	tests := []struct {
+		v    float32
+		want int
+	}{
+		{0, 0}, {0.5, 16383}, {1, 32767}, {-1, -32767}, {2, 32767}, {-2, -32767},
+	}
+	for _, tt := range tests {
+		if got := pcm16(tt.v); got != tt.want {
+			t.Errorf("pcm16(%v) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/chewxy/math32"
+)
+
+// Sink receives blocks of output samples as they are converted by the SampleRateConverter --
+// the samples slice is reused after the call returns, so it must be copied to be retained
+type Sink func(samples []float32)
+
+// SetSink sets the converter to deliver the converted samples to sink, in blocks of blockSize samples
+// (if blockSize is 0, each block is the samples converted each time the ring buffer is emptied) --
+// samples delivered to a sink are not appended to OutputData -- a nil sink turns off streaming
+func (src *SampleRateConverter) SetSink(sink Sink, blockSize int) {
+	src.FlushSink()
+	src.Sink = sink
+	src.BlockSize = blockSize
+	src.Block = src.Block[:0]
+}
+
+// FlushSink delivers any samples waiting in the current block to the sink
+func (src *SampleRateConverter) FlushSink() {
+	if src.Sink != nil && len(src.Block) > 0 {
+		src.Sink(src.Block)
+	}
+	src.Block = src.Block[:0]
+}
+
+// ChanSink returns a Sink that sends a copy of each block on the channel
+func ChanSink(ch chan<- []float32) Sink {
+	return func(samples []float32) {
+		blk := make([]float32, len(samples))
+		copy(blk, samples)
+		ch <- blk
+	}
+}
+
+// PCMWriter writes blocks of samples to an io.Writer as 16 bit signed little-endian PCM,
+// for use as a Sink via its Sink method
+type PCMWriter struct {
+	W      io.Writer
+	Scale  float32 // multiplier applied to the samples before conversion -- if 0, the samples are scaled automatically by OutputScale / the maximum absolute sample value so far
+	Err    error   // first error returned by W -- no more samples are written after an error
+	MaxVal float32 // maximum absolute sample value so far
+	buf    []byte
+}
+
+// NewPCMWriter returns a new PCMWriter writing to w with given scale (0 for automatic scaling)
+func NewPCMWriter(w io.Writer, scale float32) *PCMWriter {
+	return &PCMWriter{W: w, Scale: scale}
+}
+
+// Sink converts the samples to PCM and writes them to W -- samples beyond +/- 1 after scaling are clipped
+func (pw *PCMWriter) Sink(samples []float32) {
+	if pw.Err != nil {
+		return
+	}
+	for _, s := range samples {
+		if a := math32.Abs(s); a > pw.MaxVal {
+			pw.MaxVal = a
+		}
+	}
+	scale := pw.Scale
+	if scale == 0 {
+		if pw.MaxVal == 0 {
+			scale = 1
+		} else {
+			scale = OutputScale / pw.MaxVal
+		}
+	}
+	if cap(pw.buf) < 2*len(samples) {
+		pw.buf = make([]byte, 2*len(samples))
+	}
+	pw.buf = pw.buf[:2*len(samples)]
+	for i, s := range samples {
+		v := s * scale
+		if v > 1 {
+			v = 1
+		} else if v < -1 {
+			v = -1
+		}
+		binary.LittleEndian.PutUint16(pw.buf[2*i:], uint16(int16(v*math.MaxInt16)))
+	}
+	_, pw.Err = pw.W.Write(pw.buf)
+}
+
+// StreamTo sets the synthesized output to be delivered to the sink in blocks of blockSize samples
+// (0 for blocks as converted) instead of accumulating in OutputData, for long-form and low-latency
+// synthesis -- a nil sink returns to accumulating the output in OutputData
+func (vt *VocalTract) StreamTo(sink Sink, blockSize int) {
+	vt.SampleRateConverter.SetSink(sink, blockSize)
+}
+
+// FlushStream converts the samples remaining in the sample rate converter and delivers them to the
+// sink -- call at the end of an utterance, as the converter otherwise holds back up to one buffer of samples
+func (vt *VocalTract) FlushStream() {
+	vt.SampleRateConverter.FlushBuffer()
+	vt.SampleRateConverter.FlushSink()
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// synthVowel synthesizes n control periods of a steady voiced vowel, then flushes the converter
+func synthVowel(t *testing.T, vt *VocalTract, n int) {
+	t.Helper()
+	vt.CurControl.Init()
+	vt.CurControl.GlotVol = 60
+	for i := 0; i < n; i++ {
+		if err := vt.Synthesize(false); err != nil {
+			t.Fatalf("Synthesize error: %v", err)
+		}
+	}
+	vt.FlushStream()
+}
+
+func TestStreamTo(t *testing.T) {
+	ref := newTestVocalTract(t)
+	synthVowel(t, ref, 10)
+
+	const blockSize = 512
+	vt := newTestVocalTract(t)
+	var got []float32
+	var sizes []int
+	vt.StreamTo(func(samples []float32) {
+		got = append(got, samples...)
+		sizes = append(sizes, len(samples))
+	}, blockSize)
+	synthVowel(t, vt, 10)
+
+	if len(vt.OutputData) != 0 {
+		t.Errorf("%d samples in OutputData while streaming, want 0", len(vt.OutputData))
+	}
+	if len(got) != len(ref.OutputData) {
+		t.Fatalf("%d samples streamed, want %d", len(got), len(ref.OutputData))
+	}
+	for i, v := range got {
+		if v != ref.OutputData[i] {
+			t.Fatalf("streamed sample %d = %v, want %v", i, v, ref.OutputData[i])
+		}
+	}
+	for i, n := range sizes[:len(sizes)-1] {
+		if n != blockSize {
+			t.Errorf("block %d of %d samples, want %d", i, n, blockSize)
+		}
+	}
+	if n := sizes[len(sizes)-1]; n < 1 || n > blockSize {
+		t.Errorf("last block of %d samples, want 1 to %d", n, blockSize)
+	}
+
+	// a nil sink returns to accumulating the output
+	vt.StreamTo(nil, 0)
+	n := len(got)
+	synthVowel(t, vt, 2)
+	if len(got) != n || len(vt.OutputData) == 0 {
+		t.Errorf("after StreamTo(nil): %d samples streamed and %d in OutputData, want %d and > 0", len(got)-n, len(vt.OutputData), 0)
+	}
+}
+
+func TestChanSink(t *testing.T) {
+	ch := make(chan []float32, 2)
+	sink := ChanSink(ch)
+	samples := []float32{1, 2, 3}
+	sink(samples)
+	samples[0] = 9 // the slice is reused by the converter
+	if blk := <-ch; len(blk) != 3 || blk[0] != 1 {
+		t.Errorf("ChanSink block = %v, want a copy [1 2 3]", blk)
+	}
+}
+
+// errWriter fails after ok writes, counting all the writes
+type errWriter struct {
+	ok, n int
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	ew.n++
+	if ew.n > ew.ok {
+		return 0, errors.New("write failed")
+	}
+	return len(p), nil
+}
+
+func TestPCMWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw := NewPCMWriter(&buf, 1)
+	pw.Sink([]float32{0, 0.5, -1, 2, -2})
+	want := []int16{0, 16383, -32767, 32767, -32767}
+	got := make([]int16, buf.Len()/2)
+	binary.Read(&buf, binary.LittleEndian, got)
+	if len(got) != len(want) {
+		t.Fatalf("PCM = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PCM = %v, want %v", got, want)
+			break
+		}
+	}
+	if pw.MaxVal != 2 {
+		t.Errorf("MaxVal = %v, want 2", pw.MaxVal)
+	}
+
+	// automatic scaling puts the max at OutputScale
+	buf.Reset()
+	pw = NewPCMWriter(&buf, 0)
+	pw.Sink([]float32{0.1, -0.2})
+	got = make([]int16, 2)
+	binary.Read(&buf, binary.LittleEndian, got)
+	scale := float32(OutputScale)
+	if w := int16(-scale * 32767); got[1] != w {
+		t.Errorf("automatically scaled max sample = %v, want %v", got[1], w)
+	}
+
+	ew := &errWriter{ok: 1}
+	pw = NewPCMWriter(ew, 1)
+	pw.Sink([]float32{0.1})
+	pw.Sink([]float32{0.1})
+	if pw.Err == nil {
+		t.Fatalf("no error after a failed write")
+	}
+	pw.Sink([]float32{0.1})
+	if ew.n != 2 {
+		t.Errorf("%d writes, want 2 -- no writes after an error", ew.n)
+	}
+}
@@ -0,0 +1,453 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Abbreviations are the expansions of common English abbreviations, used by NormalizeText --
+// keys are lower case, including the period -- abbreviations that are also words (e.g., in.
+// or sun.) are not included, as the period may just end a sentence (see UnitAbbreviations
+// and PrefixAbbreviations for those that can be told apart by the words around them)
+var Abbreviations = map[string]string{
+	"mr.": "mister", "mrs.": "missus", "ms.": "miz", "dr.": "doctor", "prof.": "professor",
+	"jr.": "junior", "sr.": "senior", "vs.": "versus", "etc.": "et cetera",
+	"e.g.": "for example", "i.e.": "that is", "approx.": "approximately",
+	"dept.": "department", "inc.": "incorporated", "ltd.": "limited",
+	"ave.": "avenue", "rd.": "road", "mt.": "mount",
+	"jan.": "january", "feb.": "february", "apr.": "april", "jun.": "june",
+	"jul.": "july", "aug.": "august", "sep.": "september", "sept.": "september", "oct.": "october",
+	"nov.": "november", "dec.": "december",
+	"mon.": "monday", "tue.": "tuesday", "tues.": "tuesday", "thu.": "thursday",
+	"thurs.": "thursday", "fri.": "friday",
+}
+
+// UnitAbbreviations are the singular and plural expansions of abbreviations of units, used by
+// NormalizeText only right after a number (e.g., 5 ft. -> five feet), as elsewhere they are words
+// (e.g., come in.) -- keys are lower case, including the period if the abbreviation has one
+var UnitAbbreviations = map[string][2]string{
+	"in.": {"inch", "inches"}, "ft.": {"foot", "feet"}, "lb.": {"pound", "pounds"}, "lbs.": {"pound", "pounds"},
+	"oz.": {"ounce", "ounces"}, "min.": {"minute", "minutes"}, "sec.": {"second", "seconds"},
+	"hr.": {"hour", "hours"}, "hrs.": {"hour", "hours"}, "km": {"kilometer", "kilometers"},
+	"kg": {"kilogram", "kilograms"}, "cm": {"centimeter", "centimeters"}, "mm": {"millimeter", "millimeters"},
+}
+
+// PrefixAbbreviations are the expansions of abbreviations that are used by NormalizeText only
+// before a number or a capitalized name (e.g., No. 5 -> number five, St. Louis -> saint louis),
+// as elsewhere they are words (e.g., no.) -- keys are lower case, including the period
+var PrefixAbbreviations = map[string]string{
+	"no.": "number", "st.": "saint",
+}
+
+// months are the names of the months, after which a number is read as an ordinal (e.g., june 5 -> june fifth)
+var months = map[string]bool{
+	"january": true, "february": true, "march": true, "april": true, "may": true, "june": true,
+	"july": true, "august": true, "september": true, "october": true, "november": true, "december": true,
+}
+
+// symbolWords are the words for symbols that stand alone or are attached to words
+var symbolWords = map[rune]string{
+	'&': "and", '+': "plus", '=': "equals", '@': "at", '%': "percent", '#': "number",
+}
+
+// currencies are the singular and plural names of the main and fractional units of currency symbols
+var currencies = map[rune][4]string{
+	'$': {"dollar", "dollars", "cent", "cents"},
+	'£': {"pound", "pounds", "penny", "pence"},
+	'€': {"euro", "euros", "cent", "cents"},
+	'¥': {"yen", "yen", "sen", "sen"},
+}
+
+// letterNames are the names of the letters, used for spelling out acronyms
+var letterNames = []string{
+	"ay", "bee", "see", "dee", "ee", "eff", "gee", "aitch", "eye", "jay", "kay", "ell", "em",
+	"en", "oh", "pee", "cue", "are", "ess", "tee", "you", "vee", "double you", "ex", "why", "zee",
+}
+
+var onesWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+
+var tensWords = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+
+var scaleWords = []string{"", "thousand", "million", "billion", "trillion", "quadrillion", "quintillion"}
+
+// NumberWords returns the English words for the integer, e.g., 1234 -> one thousand two hundred thirty four
+func NumberWords(n int64) string {
+	if n < 0 {
+		return "minus " + NumberWords(-n)
+	}
+	if n < 20 {
+		return onesWords[n]
+	}
+	var grps []string
+	for sc := 0; n > 0; sc++ {
+		g := int(n % 1000)
+		n /= 1000
+		if g == 0 {
+			continue
+		}
+		w := hundredsWords(g)
+		if scaleWords[sc] != "" {
+			w += " " + scaleWords[sc]
+		}
+		grps = append([]string{w}, grps...)
+	}
+	return strings.Join(grps, " ")
+}
+
+// hundredsWords returns the words for 1 <= n < 1000
+func hundredsWords(n int) string {
+	var ws []string
+	if n >= 100 {
+		ws = append(ws, onesWords[n/100], "hundred")
+		n %= 100
+	}
+	if n >= 20 {
+		ws = append(ws, tensWords[n/10])
+		n %= 10
+	}
+	if n > 0 {
+		ws = append(ws, onesWords[n])
+	}
+	return strings.Join(ws, " ")
+}
+
+// OrdinalWords returns the English ordinal words for the integer, e.g., 21 -> twenty first
+func OrdinalWords(n int64) string {
+	ws := strings.Fields(NumberWords(n))
+	lst := ws[len(ws)-1]
+	irr := map[string]string{"one": "first", "two": "second", "three": "third", "five": "fifth",
+		"eight": "eighth", "nine": "ninth", "twelve": "twelfth"}
+	switch {
+	case irr[lst] != "":
+		lst = irr[lst]
+	case strings.HasSuffix(lst, "y"):
+		lst = strings.TrimSuffix(lst, "y") + "ieth"
+	default:
+		lst += "th"
+	}
+	ws[len(ws)-1] = lst
+	return strings.Join(ws, " ")
+}
+
+// YearWords returns the words for a year as it is usually read, e.g., 1999 -> nineteen ninety nine,
+// 2005 -> two thousand five, 2020 -> twenty twenty
+func YearWords(n int64) string {
+	hi := n / 100
+	lo := n % 100
+	switch {
+	case n%1000 < 10 && n >= 2000 || n < 1100:
+		return NumberWords(n)
+	case lo == 0:
+		return NumberWords(hi) + " hundred"
+	case lo < 10:
+		return NumberWords(hi) + " oh " + NumberWords(lo)
+	}
+	return NumberWords(hi) + " " + NumberWords(lo)
+}
+
+// DigitWords returns the words for each of the digits in the string, e.g., for the part of a number
+// after the decimal point
+func DigitWords(ds string) string {
+	var ws []string
+	for _, d := range ds {
+		if d >= '0' && d <= '9' {
+			ws = append(ws, onesWords[d-'0'])
+		}
+	}
+	return strings.Join(ws, " ")
+}
+
+// numberTokenWords returns the words for a token that starts with a digit: integers (with optional
+// thousands commas), decimals, ordinals (1st, 22nd), percentages, times (3:30) and years (if
+// year is true and the number could be a year) -- false if it is not a number
+func numberTokenWords(tok string, year, ordinal bool) (string, bool) {
+	if strings.HasSuffix(tok, "%") {
+		ws, ok := numberTokenWords(strings.TrimSuffix(tok, "%"), false, false)
+		return ws + " percent", ok
+	}
+	if ci := strings.Index(tok, ":"); ci > 0 { // time
+		h, err1 := strconv.ParseInt(tok[:ci], 10, 64)
+		m, err2 := strconv.ParseInt(tok[ci+1:], 10, 64)
+		if err1 != nil || err2 != nil {
+			return "", false
+		}
+		switch {
+		case m == 0:
+			return NumberWords(h) + " o'clock", true
+		case m < 10:
+			return NumberWords(h) + " oh " + NumberWords(m), true
+		}
+		return NumberWords(h) + " " + NumberWords(m), true
+	}
+	low := strings.ToLower(tok)
+	for _, sfx := range []string{"st", "nd", "rd", "th"} {
+		if strings.HasSuffix(low, sfx) {
+			n, err := strconv.ParseInt(strings.Replace(tok[:len(tok)-2], ",", "", -1), 10, 64)
+			if err != nil {
+				return "", false
+			}
+			return OrdinalWords(n), true
+		}
+	}
+	ip := tok
+	fp := ""
+	if di := strings.Index(tok, "."); di >= 0 {
+		ip = tok[:di]
+		fp = tok[di+1:]
+	}
+	hasComma := strings.Contains(ip, ",")
+	n, err := strconv.ParseInt(strings.Replace(ip, ",", "", -1), 10, 64)
+	if err != nil {
+		return "", false
+	}
+	var ws string
+	switch {
+	case ordinal && fp == "":
+		ws = OrdinalWords(n)
+	case year && fp == "" && !hasComma && len(ip) == 4 && n >= 1100 && n < 2100:
+		ws = YearWords(n)
+	default:
+		ws = NumberWords(n)
+	}
+	if fp != "" {
+		ws += " point " + DigitWords(fp)
+	}
+	return ws, true
+}
+
+// currencyWords returns the words for an amount of currency, e.g., 5.50 dollars -> five dollars and fifty cents
+func currencyWords(amt string, cur [4]string) (string, bool) {
+	ip := amt
+	fp := ""
+	if di := strings.Index(amt, "."); di >= 0 {
+		ip = amt[:di]
+		fp = amt[di+1:]
+	}
+	n, err := strconv.ParseInt(strings.Replace(ip, ",", "", -1), 10, 64)
+	if err != nil {
+		return "", false
+	}
+	ws := NumberWords(n) + " " + cur[1]
+	if n == 1 {
+		ws = "one " + cur[0]
+	}
+	if fp != "" {
+		if len(fp) == 1 {
+			fp += "0"
+		}
+		c, err := strconv.ParseInt(fp[:2], 10, 64)
+		if err != nil {
+			return "", false
+		}
+		if c > 0 {
+			cw := NumberWords(c) + " " + cur[3]
+			if c == 1 {
+				cw = "one " + cur[2]
+			}
+			if n == 0 {
+				return cw, true
+			}
+			ws += " and " + cw
+		}
+	}
+	return ws, true
+}
+
+// isAcronym returns true if the word is all upper case letters without any vowels, so it can't be
+// pronounced as a word, e.g., BBC
+func isAcronym(w string) bool {
+	if len(w) < 2 {
+		return false
+	}
+	for _, r := range w {
+		if r < 'A' || r > 'Z' || strings.ContainsRune("AEIOUY", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeText converts text into a string of lower-case words for SynthWords: numbers, ordinals,
+// currency, percentages, times and years are expanded into words, abbreviations are expanded,
+// acronyms without vowels are spelled out and symbols are replaced by words. The punctuation that
+// ends a phrase (, ; : . ! ?) is kept at the end of the last word of the phrase, where SynthWords
+// uses it to end a tone group, and all other punctuation is removed
+func NormalizeText(text string) string {
+	var out []string
+	toks := strings.Fields(text)
+	prev := ""
+	prevNum := "" // the previous token, if it is a number
+	for ti, tok := range toks {
+		// split off leading and trailing punctuation
+		lead := ""
+		for len(tok) > 0 && strings.ContainsRune("\"'([{", rune(tok[0])) {
+			tok = tok[1:]
+		}
+		trail := ""
+		for len(tok) > 0 {
+			c := rune(tok[len(tok)-1])
+			if !strings.ContainsRune(",;:.!?\"')]}", c) {
+				break
+			}
+			trail = string(c) + trail
+			tok = tok[:len(tok)-1]
+		}
+		if len(tok) > 0 && strings.ContainsRune("-+", rune(tok[0])) && len(tok) > 1 && unicode.IsDigit(rune(tok[1])) {
+			if tok[0] == '-' {
+				lead = "minus"
+			}
+			tok = tok[1:]
+		}
+
+		low := strings.ToLower(tok)
+		// abbreviations keep their period, so it does not end a phrase unless it ends the text
+		ab, abPer := "", false
+		if strings.HasPrefix(trail, ".") {
+			ab, abPer = Abbreviations[low+"."]
+			if un, ok := UnitAbbreviations[low+"."]; ok && prevNum != "" {
+				ab, abPer = unitWord(un, prevNum), true
+			}
+			if pa, ok := PrefixAbbreviations[low+"."]; ok && ti < len(toks)-1 && startsNumberOrName(toks[ti+1]) {
+				ab, abPer = pa, true
+			}
+		}
+		if un, ok := UnitAbbreviations[low]; ok && prevNum != "" {
+			ab = unitWord(un, prevNum)
+		}
+		if abPer {
+			trail = trail[1:]
+			if ti == len(toks)-1 {
+				trail = "." + trail
+			}
+		}
+
+		var ws []string
+		if lead != "" {
+			ws = append(ws, lead)
+		}
+		curNum := ""
+		switch {
+		case tok == "":
+		case ab != "":
+			ws = append(ws, ab)
+		case strings.ContainsRune("$£€¥", []rune(tok)[0]):
+			r := []rune(tok)
+			if cw, ok := currencyWords(string(r[1:]), currencies[r[0]]); ok {
+				ws = append(ws, cw)
+			} else {
+				ws = append(ws, wordTokens(string(r[1:]))...)
+			}
+		case unicode.IsDigit(rune(tok[0])):
+			if nw, ok := numberTokenWords(tok, true, months[prev]); ok {
+				ws = append(ws, nw)
+				if trail == "" {
+					curNum = tok
+				}
+			} else {
+				ws = append(ws, wordTokens(tok)...)
+			}
+		default:
+			ws = append(ws, wordTokens(tok)...)
+		}
+		if len(ws) > 0 {
+			prev = low
+			if ab != "" {
+				prev = ab
+			}
+		}
+		prevNum = curNum
+
+		// keep the last phrase-ending punctuation, if any, except that a ? is always kept (e.g., ?!)
+		// so the phrase is still a question
+		punct := ""
+		for _, c := range trail {
+			if strings.ContainsRune(",;:.!?", c) && punct != "?" {
+				punct = string(c)
+			}
+		}
+		wds := strings.Fields(strings.Join(ws, " "))
+		if len(wds) == 0 {
+			if punct != "" && len(out) > 0 && !strings.ContainsAny(out[len(out)-1][len(out[len(out)-1])-1:], ",;:.!?") {
+				out[len(out)-1] += punct
+			}
+			continue
+		}
+		wds[len(wds)-1] += punct
+		out = append(out, wds...)
+	}
+	return strings.Join(out, " ")
+}
+
+// unitWord returns the singular expansion of the unit abbreviation after the number 1, and the plural otherwise
+func unitWord(un [2]string, num string) string {
+	if num == "1" {
+		return un[0]
+	}
+	return un[1]
+}
+
+// startsNumberOrName returns true if the token, after any leading quotes or brackets, starts
+// with a digit or an upper case letter
+func startsNumberOrName(tok string) bool {
+	tok = strings.TrimLeft(tok, "\"'([{")
+	if tok == "" {
+		return false
+	}
+	r := []rune(tok)[0]
+	return unicode.IsDigit(r) || unicode.IsUpper(r)
+}
+
+// wordTokens splits a token into lower-case words at characters other than letters, digits and
+// apostrophes, replacing symbols with words, spelling out acronyms and expanding embedded numbers
+func wordTokens(tok string) []string {
+	var ws []string
+	var cur []rune
+	flush := func() {
+		if len(cur) == 0 {
+			return
+		}
+		w := string(cur)
+		cur = cur[:0]
+		switch {
+		case unicode.IsDigit([]rune(w)[0]):
+			if nw, ok := numberTokenWords(w, false, false); ok {
+				ws = append(ws, nw)
+			} else {
+				ws = append(ws, DigitWords(w))
+			}
+		case isAcronym(w):
+			for _, r := range w {
+				ws = append(ws, letterNames[r-'A'])
+			}
+		default:
+			ws = append(ws, strings.ToLower(strings.Trim(w, "'")))
+		}
+	}
+	for _, r := range tok {
+		switch {
+		case unicode.IsLetter(r) || r == '\'':
+			if len(cur) > 0 && unicode.IsDigit(cur[len(cur)-1]) {
+				flush()
+			}
+			cur = append(cur, r)
+		case unicode.IsDigit(r) || (r == '.' || r == ',' || r == ':') && len(cur) > 0 && unicode.IsDigit(cur[len(cur)-1]):
+			if len(cur) > 0 && !unicode.IsDigit(cur[len(cur)-1]) && !strings.ContainsRune(".,:", cur[len(cur)-1]) {
+				flush()
+			}
+			cur = append(cur, r)
+		default:
+			flush()
+			if sw, ok := symbolWords[r]; ok {
+				ws = append(ws, sw)
+			}
+		}
+	}
+	flush()
+	return ws
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import "testing"
+
+// normTests are NormalizeText test cases: the text and the normalized words that are wanted
+type normTests []struct {
+	text string
+	want string
+}
+
+// checkNormalize runs NormalizeText on each of the test texts
+func checkNormalize(t *testing.T, tests normTests) {
+	t.Helper()
+	for _, tt := range tests {
+		got := NormalizeText(tt.text)
+		if got != tt.want {
+			t.Errorf("NormalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTextNumbers(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"0", "zero"},
+		{"13", "thirteen"},
+		{"42", "forty two"},
+		{"101", "one hundred one"},
+		{"1000", "one thousand"},
+		{"1,234,567", "one million two hundred thirty four thousand five hundred sixty seven"},
+		{"12345", "twelve thousand three hundred forty five"},
+		{"3.14", "three point one four"},
+		{"0.5", "zero point five"},
+		{"-5", "minus five"},
+		{"-3.5", "minus three point five"},
+		{"It is -12 degrees.", "it is minus twelve degrees."},
+		{"50%", "fifty percent"},
+		{"3:05", "three oh five"},
+		{"3:00", "three o'clock"},
+		{"x=5", "x equals five"},
+	})
+}
+
+func TestNormalizeTextOrdinals(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"1st", "first"},
+		{"2nd", "second"},
+		{"3rd", "third"},
+		{"4th", "fourth"},
+		{"11th", "eleventh"},
+		{"12th", "twelfth"},
+		{"13th", "thirteenth"},
+		{"21st", "twenty first"},
+		{"22nd", "twenty second"},
+		{"100th", "one hundredth"},
+		{"1,000th", "one thousandth"},
+		{"The 3rd time.", "the third time."},
+	})
+}
+
+func TestNormalizeTextCurrency(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"$0.05", "five cents"},
+		{"$0.01", "one cent"},
+		{"$1", "one dollar"},
+		{"$1.00", "one dollar"},
+		{"$1.01", "one dollar and one cent"},
+		{"$2.50", "two dollars and fifty cents"},
+		{"$5.5", "five dollars and fifty cents"},
+		{"$1,000", "one thousand dollars"},
+		{"£3", "three pounds"},
+		{"€1.99", "one euro and ninety nine cents"},
+		{"It costs $3.", "it costs three dollars."},
+	})
+}
+
+func TestNormalizeTextYears(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"1999", "nineteen ninety nine"},
+		{"2005", "two thousand five"},
+		{"2000", "two thousand"},
+		{"1900", "nineteen hundred"},
+		{"1905", "nineteen oh five"},
+		{"2020", "twenty twenty"},
+		{"1066", "one thousand sixty six"},
+		{"In 1999 we left.", "in nineteen ninety nine we left."},
+		{"In 2005, we left.", "in two thousand five, we left."},
+		// not years: thousands commas and decimals
+		{"1,999", "one thousand nine hundred ninety nine"},
+		{"1999.5", "one thousand nine hundred ninety nine point five"},
+	})
+}
+
+func TestNormalizeTextDates(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"June 5", "june fifth"},
+		{"June 5th", "june fifth"},
+		{"May 1, 1999", "may first, nineteen ninety nine"},
+		{"on Jan. 21, 2005.", "on january twenty first, two thousand five."},
+		{"March 3 2010", "march third twenty ten"},
+	})
+}
+
+func TestNormalizeTextCapitalization(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"Hello World", "hello world"},
+		{"HELLO", "hello"},
+		{"McDonald", "mcdonald"},
+		{"BBC", "bee bee see"},
+		{"The BBC News.", "the bee bee see news."},
+	})
+}
+
+func TestNormalizeTextPunctuation(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"Hi, there.", "hi, there."},
+		{"Yes!", "yes!"},
+		{"Why?", "why?"},
+		{"One; two: three.", "one; two: three."},
+		{"Wait...", "wait."},
+		{"Really?!", "really?"},
+		{"\"Quoted,\" he said.", "quoted, he said."},
+		{"(Aside.)", "aside."},
+		{"End ,", "end,"},
+		{"a - b", "a b"},
+		{"A&B", "a and b"},
+	})
+}
+
+func TestSynthTextPauses(t *testing.T) {
+	// the phrase and sentence pauses lengthen the silence between the words
+	var lens []float32
+	var vt *VocalTract
+	for _, text := range []string{"one two", "one, two", "one. two"} {
+		vt = newTestVocalTract(t)
+		if err := vt.SynthText(text, true, false); err != nil {
+			t.Fatalf("SynthText(%q) error: %v", text, err)
+		}
+		lens = append(lens, float32(len(vt.OutputData))/float32(vt.Config.OutputRate)*1000)
+	}
+	if lens[1] <= lens[0] {
+		t.Errorf("one, two lasts %v ms, not longer than one two: %v ms", lens[1], lens[0])
+	}
+	want := vt.Prosody.SentPause - vt.Prosody.PhrasePause
+	if d := lens[2] - lens[1]; d < want-vt.Duration || d > want+vt.Duration {
+		t.Errorf("one. two lasts %v ms longer than one, two, want %v ms", d, want)
+	}
+}
+
+func TestNormalizeTextAbbreviations(t *testing.T) {
+	checkNormalize(t, normTests{
+		{"Come in.", "come in."},
+		{"The answer is no.", "the answer is no."},
+		{"We met on the sun. It was hot.", "we met on the sun. it was hot."},
+		{"I have 5 mm of rain.", "i have five millimeters of rain."},
+		{"The mm sound.", "the mm sound."},
+		{"It is 3 in. long", "it is three inches long"},
+		{"It is 1 ft. long", "it is one foot long"},
+		{"It is 6 ft.", "it is six feet."},
+		{"It weighs 2 kg, or so.", "it weighs two kilograms, or so."},
+		{"We sat. Then we left", "we sat. then we left"},
+		{"Go to No. 5 now", "go to number five now"},
+		{"Visit St. Louis.", "visit saint louis."},
+		{"Go down the st. now", "go down the st. now"},
+		{"Dr. Smith is here.", "doctor smith is here."},
+		{"Apples, pears etc. are fruit", "apples, pears et cetera are fruit"},
+		{"On Jan. 5 we left", "on january fifth we left"},
+	})
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/goki/gi/gi"
+)
+
+// TractPlotWidth is the default width of the tract plots saved by SaveTractSVG and SaveTractPNG
+const TractPlotWidth = 640
+
+// TractPlotHeight is the default height of the tract plots saved by SaveTractSVG and SaveTractPNG
+const TractPlotHeight = 480
+
+var (
+	plotAxisColor    = color.RGBA{0x40, 0x40, 0x40, 0xff}
+	plotGridColor    = color.RGBA{0xd0, 0xd0, 0xd0, 0xff}
+	plotOralColor    = color.RGBA{0xd0, 0x50, 0x50, 0xff}
+	plotOralFill     = color.RGBA{0xf4, 0xc8, 0xc0, 0xff}
+	plotNasalColor   = color.RGBA{0x50, 0x70, 0xc0, 0xff}
+	plotNasalFill    = color.RGBA{0xc8, 0xd4, 0xf0, 0xff}
+	plotGainColor    = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	plotFormantColor = color.RGBA{0xd0, 0x50, 0x50, 0xff}
+)
+
+// plotPath is a polyline, or a polygon if it is filled
+type plotPath struct {
+	Pts    [][2]float32
+	Stroke color.RGBA
+	Fill   color.RGBA // not filled if transparent
+	Dash   bool
+}
+
+// plotLabel is a text label, anchored at start, middle or end
+type plotLabel struct {
+	X, Y   float32
+	Text   string
+	Anchor string
+}
+
+// tractPlot is the plot of the shape of the tract above its frequency response, as paths and labels in
+// pixel coordinates, which are rendered to SVG, or to an image without the labels
+type tractPlot struct {
+	Width, Height int
+	Paths         []plotPath
+	Labels        []plotLabel
+}
+
+// plotPanel maps data coordinates onto a rectangle of the plot
+type plotPanel struct {
+	X0, Y0, X1, Y1         float32 // rectangle in pixels
+	XMin, XMax, YMin, YMax float32 // data range
+}
+
+func (pp *plotPanel) pt(x, y float32) [2]float32 {
+	return [2]float32{pp.X0 + (x-pp.XMin)/(pp.XMax-pp.XMin)*(pp.X1-pp.X0), pp.Y1 - (y-pp.YMin)/(pp.YMax-pp.YMin)*(pp.Y1-pp.Y0)}
+}
+
+func (tp *tractPlot) path(pts [][2]float32, stroke, fill color.RGBA, dash bool) {
+	tp.Paths = append(tp.Paths, plotPath{Pts: pts, Stroke: stroke, Fill: fill, Dash: dash})
+}
+
+func (tp *tractPlot) label(x, y float32, text, anchor string) {
+	tp.Labels = append(tp.Labels, plotLabel{X: x, Y: y, Text: text, Anchor: anchor})
+}
+
+// axes adds the frame of the panel, with grid lines and tick labels every xStep and yStep, and axis titles
+func (tp *tractPlot) axes(pp *plotPanel, xStep, yStep float32, xTitle, yTitle string) {
+	for x := float32(math.Ceil(float64(pp.XMin/xStep))) * xStep; x <= pp.XMax; x += xStep {
+		tp.path([][2]float32{pp.pt(x, pp.YMin), pp.pt(x, pp.YMax)}, plotGridColor, color.RGBA{}, false)
+		p := pp.pt(x, pp.YMin)
+		tp.label(p[0], p[1]+14, fmt.Sprintf("%g", x), "middle")
+	}
+	for y := float32(math.Ceil(float64(pp.YMin/yStep))) * yStep; y <= pp.YMax; y += yStep {
+		tp.path([][2]float32{pp.pt(pp.XMin, y), pp.pt(pp.XMax, y)}, plotGridColor, color.RGBA{}, false)
+		p := pp.pt(pp.XMin, y)
+		tp.label(p[0]-4, p[1]+4, fmt.Sprintf("%g", y), "end")
+	}
+	tp.path([][2]float32{{pp.X0, pp.Y0}, {pp.X1, pp.Y0}, {pp.X1, pp.Y1}, {pp.X0, pp.Y1}, {pp.X0, pp.Y0}}, plotAxisColor, color.RGBA{}, false)
+	tp.label(0.5*(pp.X0+pp.X1), pp.Y1+28, xTitle, "middle")
+	tp.label(pp.X0, pp.Y0-6, yTitle, "start")
+}
+
+// newTractPlot returns the plot of the shape of the tract from the area function, as the radius of each
+// section of the oropharynx below and of the nasal tract above, over the frequency response and formants
+// of the transfer function
+func newTractPlot(af *AreaFunc, tf *TransferFunc, width, height int) *tractPlot {
+	tp := &tractPlot{Width: width, Height: height}
+	w, h := float32(width), float32(height)
+	mid := 0.45 * h
+
+	// tract shape: the oral profile is centered at -rMax and the nasal one at +rMax
+	radius := func(area float32) float32 { return float32(math.Sqrt(float64(area) / math.Pi)) }
+	rMax := float32(1)
+	for _, areas := range [][]float32{af.Areas, af.NasalAreas} {
+		for _, a := range areas {
+			if r := radius(a); r > rMax {
+				rMax = r
+			}
+		}
+	}
+	rMax = float32(math.Ceil(float64(rMax)))
+	sp := &plotPanel{X0: 50, Y0: 24, X1: w - 20, Y1: mid - 36, XMin: 0, XMax: af.Length(), YMin: -2 * rMax, YMax: 2 * rMax}
+	tp.axes(sp, 2, rMax, "distance from glottis (cm)", "radius (cm): oral below, nasal above")
+	profile := func(areas []float32, x0, yc float32) [][2]float32 {
+		var top, bot [][2]float32
+		for i, a := range areas {
+			xa, xb := x0+float32(i)*af.SectLen, x0+float32(i+1)*af.SectLen
+			r := radius(a)
+			top = append(top, sp.pt(xa, yc+r), sp.pt(xb, yc+r))
+			bot = append(bot, sp.pt(xa, yc-r), sp.pt(xb, yc-r))
+		}
+		for i := len(bot) - 1; i >= 0; i-- {
+			top = append(top, bot[i])
+		}
+		return append(top, top[0])
+	}
+	tp.path(profile(af.Areas, 0, -rMax), plotOralColor, plotOralFill, false)
+	tp.path(profile(af.NasalAreas, float32(af.VelumSect)*af.SectLen, rMax), plotNasalColor, plotNasalFill, false)
+
+	// frequency response, with the formants marked
+	gMin, gMax := float32(0), float32(0)
+	for _, g := range tf.Gains {
+		gMin = float32(math.Min(float64(gMin), float64(g)))
+		gMax = float32(math.Max(float64(gMax), float64(g)))
+	}
+	gMin = 10 * float32(math.Floor(float64(gMin/10)))
+	gMax = 10 * float32(math.Ceil(float64(gMax/10)))
+	if gMax <= gMin {
+		gMax = gMin + 10
+	}
+	fMax := float32(1)
+	if n := len(tf.Freqs); n > 0 {
+		fMax = tf.Freqs[n-1]
+	}
+	gStep := float32(10)
+	if gMax-gMin > 60 {
+		gStep = 20
+	}
+	fp := &plotPanel{X0: 50, Y0: mid + 24, X1: w - 20, Y1: h - 36, XMin: 0, XMax: fMax, YMin: gMin, YMax: gMax}
+	tp.axes(fp, 1000, gStep, "frequency (Hz)", "gain (dB)")
+	for i, fm := range tf.Formants {
+		p0, p1 := fp.pt(fm.Freq, gMin), fp.pt(fm.Freq, gMax)
+		tp.path([][2]float32{p0, p1}, plotFormantColor, color.RGBA{}, true)
+		tp.label(p1[0]+3, p1[1]+12, fmt.Sprintf("F%d %.0f", i+1, fm.Freq), "start")
+	}
+	pts := make([][2]float32, len(tf.Freqs))
+	for i := range tf.Freqs {
+		pts[i] = fp.pt(tf.Freqs[i], tf.Gains[i])
+	}
+	tp.path(pts, plotGainColor, color.RGBA{}, false)
+	return tp
+}
+
+// svgColor returns the color in the #rrggbb form
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// writeSVG writes the plot as an SVG document
+func (tp *tractPlot) writeSVG(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", tp.Width, tp.Height, tp.Width, tp.Height)
+	fmt.Fprintf(&sb, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+	for _, p := range tp.Paths {
+		fill := "none"
+		if p.Fill.A != 0 {
+			fill = svgColor(p.Fill)
+		}
+		dash := ""
+		if p.Dash {
+			dash = " stroke-dasharray=\"4,3\""
+		}
+		fmt.Fprintf(&sb, "<polyline fill=\"%s\" stroke=\"%s\"%s points=\"", fill, svgColor(p.Stroke), dash)
+		for i, pt := range p.Pts {
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			fmt.Fprintf(&sb, "%.1f,%.1f", pt[0], pt[1])
+		}
+		sb.WriteString("\"/>\n")
+	}
+	for _, l := range tp.Labels {
+		text := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(l.Text)
+		fmt.Fprintf(&sb, "<text x=\"%.1f\" y=\"%.1f\" font-family=\"sans-serif\" font-size=\"11\" text-anchor=\"%s\">%s</text>\n", l.X, l.Y, l.Anchor, text)
+	}
+	sb.WriteString("</svg>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// image renders the paths of the plot, without the labels, as there is no font renderer
+func (tp *tractPlot) image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, tp.Width, tp.Height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for _, p := range tp.Paths {
+		if p.Fill.A != 0 {
+			fillPolygon(img, p.Pts, p.Fill)
+		}
+		for i := 1; i < len(p.Pts); i++ {
+			drawLine(img, p.Pts[i-1], p.Pts[i], p.Stroke, p.Dash)
+		}
+	}
+	return img
+}
+
+// fillPolygon fills the polygon in the image, by the even-odd rule, sampling at the center of each pixel
+func fillPolygon(img *image.RGBA, pts [][2]float32, c color.RGBA) {
+	b := img.Bounds()
+	var xs []float32
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		yc := float32(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			p0, p1 := pts[i], pts[(i+1)%len(pts)]
+			if (p0[1] <= yc) != (p1[1] <= yc) {
+				xs = append(xs, p0[0]+(yc-p0[1])/(p1[1]-p0[1])*(p1[0]-p0[0]))
+			}
+		}
+		for i := 1; i < len(xs); i++ { // insertion sort -- there are few crossings
+			for j := i; j > 0 && xs[j] < xs[j-1]; j-- {
+				xs[j], xs[j-1] = xs[j-1], xs[j]
+			}
+		}
+		for i := 0; i+1 < len(xs); i += 2 {
+			for x := int(math.Ceil(float64(xs[i] - 0.5))); float32(x)+0.5 < xs[i+1]; x++ {
+				if x >= b.Min.X && x < b.Max.X {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+}
+
+// drawLine draws a line one pixel wide in the image, dashed in 4 on, 3 off pixel steps if dash
+func drawLine(img *image.RGBA, p0, p1 [2]float32, c color.RGBA, dash bool) {
+	dx, dy := p1[0]-p0[0], p1[1]-p0[1]
+	n := int(math.Ceil(math.Max(math.Abs(float64(dx)), math.Abs(float64(dy)))))
+	for i := 0; i <= n; i++ {
+		if dash && i%7 >= 4 {
+			continue
+		}
+		t := float32(0)
+		if n > 0 {
+			t = float32(i) / float32(n)
+		}
+		x, y := int(math.Floor(float64(p0[0]+t*dx))), int(math.Floor(float64(p0[1]+t*dy)))
+		if image.Pt(x, y).In(img.Bounds()) {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// TractSVG writes an SVG plot of the shape of the tract from the area function, above the frequency
+// response and formants of the transfer function, of the given size in pixels
+func TractSVG(w io.Writer, af *AreaFunc, tf *TransferFunc, width, height int) error {
+	return newTractPlot(af, tf, width, height).writeSVG(w)
+}
+
+// TractPNG writes a PNG image of the plot of TractSVG -- the image is unlabeled: it has the shape of the
+// tract, the frequency response, the formant markers and the grid, but none of the tick labels, axis titles
+// or formant frequencies, which are only in the SVG, so use TractSVG for a labeled plot
+func TractPNG(w io.Writer, af *AreaFunc, tf *TransferFunc, width, height int) error {
+	return png.Encode(w, newTractPlot(af, tf, width, height).image())
+}
+
+// SaveTractSVG saves the SVG plot of the tract for the current control params (see Analyze and TractSVG)
+func (vt *VocalTract) SaveTractSVG(filename gi.FileName) error {
+	return vt.saveTractPlot(filename, TractSVG)
+}
+
+// SaveTractPNG saves the PNG image of the tract for the current control params (see Analyze and TractPNG) --
+// the image is unlabeled, unlike that of SaveTractSVG
+func (vt *VocalTract) SaveTractPNG(filename gi.FileName) error {
+	return vt.saveTractPlot(filename, TractPNG)
+}
+
+func (vt *VocalTract) saveTractPlot(filename gi.FileName, plot func(io.Writer, *AreaFunc, *TransferFunc, int, int) error) error {
+	af, tf, err := vt.Analyze()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(string(filename))
+	if err != nil {
+		return err
+	}
+	err = plot(f, af, tf, TractPlotWidth, TractPlotHeight)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -41,6 +41,8 @@
 package trm
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/chewxy/math32"
 	"github.com/emer/auditory/sound"
@@ -48,6 +50,7 @@ import (
 	"github.com/emer/etable/etensor"
 	"github.com/go-audio/audio"
 	"github.com/goki/gi/gi"
+	"io/ioutil"
 	"math"
 	"strings"
 )
@@ -80,8 +83,16 @@ type VocalTractConfig struct {
 	ThroatVol    float32
 	VtlOff       float32
 	MixOff       float32
-	WaveForm     WaveForm
+	WaveForm     WaveForm // glottal source waveform: the Pulse, a glottal flow model (LF, Rosenberg, KLGLOTT88) or a Sine
 	NoiseMod     bool
+	Oversample   bool    // use the 2x oversampling glottal oscillator, which reduces aliasing at high pitches
+	Rd           float32 // shape of the LF glottal flow, from 0.3 (tense) through 1 (modal) to 2.7 (breathy)
+	OpenQuot     float32 // open quotient (fraction of the period the glottis is open) of the Rosenberg and KLGLOTT88 glottal flows
+	Tilt         float32 // spectral tilt of the glottal source in dB of attenuation at 3 kHz
+	Jitter       float32 // maximum random perturbation of the period of each glottal cycle, as a fraction of the period (e.g., .01)
+	Shimmer      float32 // maximum random perturbation of the amplitude of each glottal cycle, as a fraction of the amplitude (e.g., .05)
+	NSects       int     // number of sections of the oropharynx, using the generalized Waveguide -- 0 for the standard 10 section model
+	OutputRate   int     // sample rate of the synthesized output, e.g., 16000, 22050, 44100, 48000
 }
 
 // Init calls Defaults to set the initial values
@@ -100,7 +111,13 @@ func (vtc *VocalTractConfig) Defaults() {
 	vtc.VtlOff = 0.0
 	vtc.WaveForm = Pulse
 	vtc.NoiseMod = true
+	vtc.Rd = 1.0
+	vtc.OpenQuot = 0.6
+	vtc.Tilt = 0.0
+	vtc.Jitter = 0.0
+	vtc.Shimmer = 0.0
 	vtc.MixOff = 48.0
+	vtc.OutputRate = DefaultOutputRate
 }
 
 /////////////////////////////////////////////////////
@@ -125,17 +142,17 @@ func (vp *VoiceParams) Init() {
 
 // VoiceParams are the parameters that control the quality of the voice
 type VoiceParams struct {
-	TractLength      float32
-	GlotPulseFallMin float32
-	GlotPulseFallMax float32
-	GlotPitchRef     float32
-	Breathiness      float32
-	GlotPulseRise    float32
-	ApertureRadius   float32
-	NoseRadii        [5]float32
-	Radius1          float32
-	NoseRadiusCoef   float32
-	RadiusCoef       float32
+	TractLength      float32    // length of the vocal tract in cm, which determines the internal sample rate
+	GlotPulseFallMin float32    // minimum fall time of the glottal pulse, as a percentage of the period
+	GlotPulseFallMax float32    // maximum fall time of the glottal pulse, as a percentage of the period
+	GlotPitchRef     float32    // reference pitch of the voice in semitones relative to middle C, added to the pitch of phone sequences
+	Breathiness      float32    // percentage of noise mixed into the glottal pulse
+	GlotPulseRise    float32    // rise time of the glottal pulse, as a percentage of the period
+	ApertureRadius   float32    // radius of the mouth and nose apertures in cm
+	NoseRadii        [5]float32 // radii of the fixed nasal sections 2-6 in cm
+	Radius1          float32    // radius of the fixed oropharynx region 1 in cm
+	NoseRadiusCoef   float32    // scaling of the nasal radii
+	RadiusCoef       float32    // scaling of the oropharynx radii
 }
 
 // DefaultParams are the defaults, some of which don't change
@@ -213,9 +230,28 @@ func (vp *VoiceParams) SetDefault(voice Voices) {
 	}
 }
 
-// NoseRadiusVal gets nose radius value, using *zero-based* index value
+// NoseRadiusVal gets nose radius value, using *zero-based* index value, where 0 is nasal section 2 (section 1 is the velum),
+// scaled by NoseRadiusCoef
 func (vp *VoiceParams) NoseRadiusVal(idx int) float32 {
-	return vp.NoseRadii[idx]
+	return vp.NoseRadii[idx] * vp.NoseRadiusCoef
+}
+
+// OpenJSON opens the voice params from a JSON-formatted file, e.g., as saved by SaveJSON
+func (vp *VoiceParams) OpenJSON(filename gi.FileName) error {
+	b, err := ioutil.ReadFile(string(filename))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, vp)
+}
+
+// SaveJSON saves the voice params to a JSON-formatted file
+func (vp *VoiceParams) SaveJSON(filename gi.FileName) error {
+	b, err := json.MarshalIndent(vp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(string(filename), b, 0644)
 }
 
 // get nose radius value, using *zero-based* index value (= radius_1, etc)
@@ -233,13 +269,13 @@ const (
 	FricPosIdx
 	FricCfIdx
 	FricBwIdx
-	//Radius2Idx
-	//Radius3Idx
-	//Radius4Idx
-	//Radius5Idx
-	//Radius6Idx
-	//Radius7Idx
-	//Radius8Idx
+	Radius2Idx
+	Radius3Idx
+	Radius4Idx
+	Radius5Idx
+	Radius6Idx
+	Radius7Idx
+	Radius8Idx
 	VelumIdx
 	NCtrlParams
 )
@@ -272,51 +308,70 @@ func (vtc *VocalTractCtrl) Init() {
 	vtc.Velum = 0.1
 }
 
-// ComputeDeltas
+// ParamVal returns a pointer to the control parameter at given index, in CtrlParamIdxs order
+func (vtc *VocalTractCtrl) ParamVal(idx int) *float32 {
+	switch idx {
+	case GlotPitchIdx:
+		return &vtc.GlotPitch
+	case GlotVolIdx:
+		return &vtc.GlotVol
+	case AspVolIdx:
+		return &vtc.AspVol
+	case FricVolIdx:
+		return &vtc.FricVol
+	case FricPosIdx:
+		return &vtc.FricPos
+	case FricCfIdx:
+		return &vtc.FricCf
+	case FricBwIdx:
+		return &vtc.FricBw
+	case VelumIdx:
+		return &vtc.Velum
+	}
+	return &vtc.Radii[idx-Radius2Idx]
+}
+
+// ComputeDeltas computes the per-sample change in each control parameter needed to go from
+// prvCtrl to curCtrl in 1 / ctrlFreq samples -- if deltaMax is non-nil the changes are limited to
+// +/- the deltaMax value for each parameter
 func (vtc *VocalTractCtrl) ComputeDeltas(curCtrl *VocalTractCtrl, prvCtrl *VocalTractCtrl, deltaMax *VocalTractCtrl, ctrlFreq float32) {
-	// todo:
-	// for(int i=0; i< N_PARAMS; i++) {
-	//  float cval = cur.ParamVal(i);
-	//  float pval = prv.ParamVal(i);
-	//  float dmax = del_max.ParamVal(i);
-	//  float& nval = ParamVal(i);
-	//  nval = (cval - pval) * ctrl_freq;
-	//  // if(nval > dmax) nval = dmax;
-	//  // else if (nval < -dmax) nval = -dmax;
-	// }
-}
-
-// UpdateFromDeltas
+	for i := 0; i < NCtrlParams; i++ {
+		nval := (*curCtrl.ParamVal(i) - *prvCtrl.ParamVal(i)) * ctrlFreq
+		if deltaMax != nil {
+			dmax := *deltaMax.ParamVal(i)
+			if nval > dmax {
+				nval = dmax
+			} else if nval < -dmax {
+				nval = -dmax
+			}
+		}
+		*vtc.ParamVal(i) = nval
+	}
+}
+
+// UpdateFromDeltas adds the deltas to each control parameter
 func (vtc *VocalTractCtrl) UpdateFromDeltas(deltas *VocalTractCtrl) {
-	// todo:
-	//  for(int i=0; i< N_PARAMS; i++) {
-	//    float dval = del.ParamVal(i);
-	//    float& nval = ParamVal(i);
-	//    nval += dval;
-	//  }
+	for i := 0; i < NCtrlParams; i++ {
+		*vtc.ParamVal(i) += *deltas.ParamVal(i)
+	}
 }
 
-// DefaultMaxDeltas
+// DefaultMaxDeltas sets the maximum per-sample change of each control parameter,
+// as used for rate limiting by ComputeDeltas -- the full range of each parameter
+// can be traversed in about 500 samples
 func (vtc *VocalTractCtrl) DefaultMaxDeltas() {
-	// todo:
-	//  float ctrl_freq = 1.0f / 501.0f; // default
-	//  // default to entire range ok here for now.. fix when glitches encountered..
-	//  glot_pitch = 10.0f * ctrl_freq;
-	//  glot_vol = 60.0f * ctrl_freq;
-	//  asp_vol = 10.0f * ctrl_freq;
-	//  fric_vol = 24.0f * ctrl_freq;
-	//  fric_pos = 7.0f * ctrl_freq;
-	//  fric_cf = 3000.0f * ctrl_freq;
-	//  fric_bw = 4000.0f * ctrl_freq;
-	//  radius_2 = 3.0f * ctrl_freq;
-	//  radius_3 = 3.0f * ctrl_freq;
-	//  radius_4 = 3.0f * ctrl_freq;
-	//  radius_5 = 3.0f * ctrl_freq;
-	//  radius_6 = 3.0f * ctrl_freq;
-	//  radius_7 = 3.0f * ctrl_freq;
-	//  radius_8 = 3.0f * ctrl_freq;
-	//  velum = 1.5f * ctrl_freq;
-
+	ctrlFreq := float32(1.0 / 501.0)
+	vtc.GlotPitch = 10.0 * ctrlFreq
+	vtc.GlotVol = 60.0 * ctrlFreq
+	vtc.AspVol = 10.0 * ctrlFreq
+	vtc.FricVol = 24.0 * ctrlFreq
+	vtc.FricPos = 7.0 * ctrlFreq
+	vtc.FricCf = 3000.0 * ctrlFreq
+	vtc.FricBw = 4000.0 * ctrlFreq
+	for i := range vtc.Radii {
+		vtc.Radii[i] = 3.0 * ctrlFreq
+	}
+	vtc.Velum = 1.5 * ctrlFreq
 }
 
 // SetFromParams
@@ -328,14 +383,73 @@ func (vtc *VocalTractCtrl) SetFromParams(vtcOther *VocalTractCtrl) {
 	vtc.FricPos = vtcOther.FricPos
 	vtc.FricCf = vtcOther.FricCf
 	vtc.FricBw = vtcOther.FricBw
-	for i, _ := range vtc.Radii {
-		vtc.Radii[i] = vtc.Radii[i]
+	for i := range vtc.Radii {
+		vtc.Radii[i] = vtcOther.Radii[i]
 	}
 	vtc.Velum = vtcOther.Velum
 }
 
-// SetFromValues - order must be preserved!
-func (vtc *VocalTractCtrl) SetFromValues(values []float32) {
+// DefaultMins sets the minimum value of each control parameter, as in the gnuspeech (Monet) parameter
+// ranges -- used for validation and clamping (see CheckCtrl) and for normalized values
+func (vtc *VocalTractCtrl) DefaultMins() {
+	vtc.GlotPitch = -20.0
+	vtc.GlotVol = 0.0
+	vtc.AspVol = 0.0
+	vtc.FricVol = 0.0
+	vtc.FricPos = 0.0
+	vtc.FricCf = 100.0
+	vtc.FricBw = 250.0
+	for i := range vtc.Radii {
+		vtc.Radii[i] = 0.0
+	}
+	vtc.Velum = 0.1
+}
+
+// DefaultMaxs sets the maximum value of each control parameter, as in the gnuspeech (Monet) parameter
+// ranges -- used for validation and clamping (see CheckCtrl) and for normalized values
+func (vtc *VocalTractCtrl) DefaultMaxs() {
+	vtc.GlotPitch = 10.0
+	vtc.GlotVol = 60.0
+	vtc.AspVol = 10.0
+	vtc.FricVol = 24.0
+	vtc.FricPos = 7.0
+	vtc.FricCf = 6000.0
+	vtc.FricBw = 4000.0
+	for i := range vtc.Radii {
+		vtc.Radii[i] = 3.0
+	}
+	vtc.Velum = 1.5
+}
+
+// SetFromNormValues sets the params from normalized (0-1) values, in CtrlParamIdxs order,
+// mapped onto the range from min to max of each param
+func (vtc *VocalTractCtrl) SetFromNormValues(values []float32, min, max *VocalTractCtrl) {
+	for i := 0; i < NCtrlParams; i++ {
+		mn := *min.ParamVal(i)
+		*vtc.ParamVal(i) = mn + values[i]*(*max.ParamVal(i)-mn)
+	}
+}
+
+// NormValues returns the params as normalized (0-1) values, in CtrlParamIdxs order,
+// relative to the range from min to max of each param
+func (vtc *VocalTractCtrl) NormValues(min, max *VocalTractCtrl) []float32 {
+	vals := make([]float32, NCtrlParams)
+	for i := range vals {
+		mn := *min.ParamVal(i)
+		rng := *max.ParamVal(i) - mn
+		if rng != 0 {
+			vals[i] = (*vtc.ParamVal(i) - mn) / rng
+		}
+	}
+	return vals
+}
+
+// SetFromValues sets the params from values in CtrlParamIdxs order -- returns a CtrlCountError,
+// leaving the params unchanged, if there are fewer than NCtrlParams values
+func (vtc *VocalTractCtrl) SetFromValues(values []float32) error {
+	if len(values) < NCtrlParams {
+		return &CtrlCountError{N: len(values)}
+	}
 	vtc.GlotPitch = values[0]
 	vtc.GlotVol = values[1]
 	vtc.AspVol = values[2]
@@ -347,6 +461,7 @@ func (vtc *VocalTractCtrl) SetFromValues(values []float32) {
 		vtc.Radii[i] = values[i+7]
 	}
 	vtc.Velum = values[14]
+	return nil
 }
 
 func (vtc *VocalTractCtrl) RadiusVal(idx int) float32 {
@@ -477,7 +592,7 @@ const (
 type VocalTract struct {
 	AudioBuf     sound.Wave
 	Volume       float32
-	Balance      float32
+	Balance      float32 // stereo panning, from -1 (left) to 1 (right), for Stereo output
 	Duration     float32 // duration of synthesized sound
 	Config       VocalTractConfig
 	Voice        VoiceParams
@@ -485,8 +600,18 @@ type VocalTract struct {
 	PrevControl  VocalTractCtrl
 	DeltaControl VocalTractCtrl
 	DeltaMax     VocalTractCtrl
+	RateLimit    bool           // limit the per-sample change of each control parameter to DeltaMax
+	CtrlMin      VocalTractCtrl // minimum of each control parameter, for validation and normalized values
+	CtrlMax      VocalTractCtrl // maximum of each control parameter, for validation and normalized values
+	ClampCtrl    bool           // clamp control parameters to the CtrlMin - CtrlMax range, instead of returning an error
 	PhoneTable   etable.Table
 	DictTable    etable.Table
+	Postures     PostureSet  // postures for each phone in the PhoneTable
+	Rules        RuleSet     // rules for generating the control trajectories of phone sequences
+	Prosody      Prosody     // rhythm and intonation applied to phone sequences
+	Recorder     Recorder    // optional recording of the articulatory state during synthesis
+	Morph        VoiceMorph  // optional morphing of the voice over time
+	Spatializer  Spatializer // optional placement of the source around the head for Stereo output
 
 	// derived values
 	ControlRate      float32 // 1.0-1000.0 input tables/second (Hz)
@@ -498,6 +623,7 @@ type VocalTract struct {
 
 	// tube and tube coefficients
 	Oropharynx      [OroPharynxSectCount][2][2]float32
+	Waveguide       Waveguide // generalized oropharynx of Config.NSects sections, used instead of Oropharynx if NSects > 0
 	OropharynxCoefs [OroPharynxCoefCount]float32
 	Nasal           [NasalTractSectCount][2][2]float32
 	NasalCoefs      [NasalTractCoefCount]float32
@@ -527,119 +653,348 @@ type VocalTract struct {
 	NoiseSource           NoiseSource
 }
 
-// Init gets us going - this is the first function to call
-func (vt *VocalTract) Init() {
-	vt.SampleRate = 44100
+// Init gets us going - this is the first function to call -- the Voice is the Male voice unless
+// it has been set beforehand (e.g., by Voice.Init, which sets the Female voice, Voice.SetDefault
+// or Voice.OpenJSON) -- the Config is used as is, so to change any of its values call
+// Config.Defaults first and then set them, and a Config that has not been set at all gets its
+// Defaults, as do the CtrlMin - CtrlMax ranges -- returns the InitializeSynthesizer error, if any
+func (vt *VocalTract) Init() error {
+	if vt.Config == (VocalTractConfig{}) {
+		vt.Config.Defaults()
+	}
+	vt.SampleRate = vt.Config.OutputRate
 	vt.Duration = 25
-	vt.Voice.SetDefault(Male)
+	if vt.Voice.TractLength == 0 {
+		vt.Voice.SetDefault(Male)
+	}
+	if vt.CtrlMin == (VocalTractCtrl{}) && vt.CtrlMax == (VocalTractCtrl{}) {
+		vt.CtrlMin.DefaultMins()
+		vt.CtrlMax.DefaultMaxs()
+	}
 	vt.InitBuffer()
 	vt.Reset()
 	ctrlRate := 1.0 / (vt.Duration / 1000.0)
 	vt.ControlRate = ctrlRate
-	vt.InitializeSynthesizer()
+	err := vt.InitializeSynthesizer()
 	vt.PrevControl.SetFromParams(&vt.CurControl) // no deltas if reset
 	vt.CurrentData.SetFromParams(&vt.CurControl)
+	return err
+}
+
+// SetVoice sets the Voice to the default params of the given voice type and
+// reinitializes the synthesizer for the voice, returning the InitSynth error, if any
+func (vt *VocalTract) SetVoice(voice Voices) error {
+	vt.Voice.SetDefault(voice)
+	return vt.InitSynth()
+}
+
+// SetVoiceParams sets the Voice to the given params, e.g., a variant of one of the
+// default voices, and reinitializes the synthesizer for the voice, returning the InitSynth error, if any
+func (vt *VocalTract) SetVoiceParams(vp *VoiceParams) error {
+	vt.Voice = *vp
+	return vt.InitSynth()
 }
 
-func (vt *VocalTract) ControlFromDataTable(col etensor.Tensor, row int, normalized bool) {
-	params := col.SubSpace([]int{row}).(*etensor.Float32)
-	vt.CurControl.SetFromValues(params.Values)
-}
-
-//void VocalTract::SynthFromDataTable(const DataTable& table, const Variant& col, int row,
-//                                   bool normalized, bool reset_first) {
-// float_MatrixPtr mtx;
-// mtx = (float_Matrix*)table.GetValAsMatrix(col, row);
-// if(TestError(!(bool)mtx, "SynthFromDataTable", "matrix column not found")) {
-//   return;
-// }
-// if(mtx->dims() == 2 && mtx->dim(0) == VocalTractCtrl::N_PARAMS) {
-//   // multi-dim case..
-//   int n_outer = mtx->dim(1);
-//   for(int i=0; i< n_outer; i++) {
-//     float_MatrixPtr frm;
-//     frm = (float_Matrix*)mtx->GetFrameSlice(i);
-//     CtrlFromMatrix(*frm, normalized);
-//     Synthesize(reset_first && (i == 0));
-//   }
-// }
-// else {
-//   // one-shot
-//   cur_ctrl.SetFromDataTable(table, col, row, normalized);
-//   Synthesize(reset_first);
-// }
-//}
-//
-
-// LoadEnglishPhones loads the file of English phones
-func (vt *VocalTract) LoadEnglishPhones() {
-	fn := gi.FileName("VocalTractEnglishPhones.dat")
+// OpenVoice opens the Voice params from a JSON-formatted file and reinitializes the synthesizer for the voice
+func (vt *VocalTract) OpenVoice(filename gi.FileName) error {
+	vp := vt.Voice
+	if err := vp.OpenJSON(filename); err != nil {
+		return err
+	}
+	if vp.TractLength <= 0 {
+		return fmt.Errorf("trm.OpenVoice: voice in %v has invalid TractLength %v", filename, vp.TractLength)
+	}
+	return vt.SetVoiceParams(&vp)
+}
+
+// SaveVoice saves the Voice params to a JSON-formatted file
+func (vt *VocalTract) SaveVoice(filename gi.FileName) error {
+	return vt.Voice.SaveJSON(filename)
+}
+
+// ControlFromDataTable sets the current control parameters from the NCtrlParams values in the cell of
+// col at row -- if normalized, the values are in the 0-1 range and are mapped onto the CtrlMin - CtrlMax
+// range of each parameter (see SetFromNormValues)
+func (vt *VocalTract) ControlFromDataTable(col etensor.Tensor, row int, normalized bool) error {
+	cell := col.SubSpace([]int{row})
+	if cell == nil || cell.Len() < NCtrlParams {
+		return fmt.Errorf("trm.ControlFromDataTable: cell at row %v does not have %v control values", row, NCtrlParams)
+	}
+	vals := make([]float32, NCtrlParams)
+	for i := range vals {
+		vals[i] = float32(cell.FloatVal1D(i))
+	}
+	return vt.CtrlFromValues(vals, normalized)
+}
+
+// CtrlFromValues sets the current control parameters from values in CtrlParamIdxs order,
+// which are in the 0-1 range if normalized -- the params are checked with CheckCtrl, and
+// are unchanged if there are too few values or any is out of range
+func (vt *VocalTract) CtrlFromValues(vals []float32, normalized bool) error {
+	ctrl := vt.CurControl
+	if normalized {
+		if len(vals) < NCtrlParams {
+			return &CtrlCountError{N: len(vals)}
+		}
+		ctrl.SetFromNormValues(vals, &vt.CtrlMin, &vt.CtrlMax)
+	} else if err := ctrl.SetFromValues(vals); err != nil {
+		return err
+	}
+	return vt.SetCtrl(&ctrl)
+}
+
+// SynthFromDataTable synthesizes from the control parameters in the cell of col at row, which is either
+// a single vector of NCtrlParams values, synthesized for one control period (Duration), or a matrix of
+// shape [NCtrlParams, frames] (or [frames, NCtrlParams]), synthesized one frame per control period --
+// a square [NCtrlParams, NCtrlParams] matrix is taken as [NCtrlParams, frames].
+// If normalized, the values are in the 0-1 range (see ControlFromDataTable).
+// If resetFirst, the tract is reset before the first frame.
+func (vt *VocalTract) SynthFromDataTable(col etensor.Tensor, row int, normalized, resetFirst bool) error {
+	if col == nil {
+		return errors.New("trm.SynthFromDataTable: column is nil")
+	}
+	if row < 0 || row >= col.Dim(0) {
+		return fmt.Errorf("trm.SynthFromDataTable: row %v out of range (%v rows)", row, col.Dim(0))
+	}
+	shp := col.Shapes()[1:]
+	if len(shp) != 2 {
+		if err := vt.ControlFromDataTable(col, row, normalized); err != nil {
+			return err
+		}
+		return vt.Synthesize(resetFirst)
+	}
+	cell := col.SubSpace([]int{row})
+	var nfr, pstr, fstr int // number of frames, stride between params, stride between frames
+	switch {
+	case shp[0] == NCtrlParams:
+		nfr, pstr, fstr = shp[1], shp[1], 1
+	case shp[1] == NCtrlParams:
+		nfr, pstr, fstr = shp[0], 1, NCtrlParams
+	default:
+		return fmt.Errorf("trm.SynthFromDataTable: cell shape %v is not [%v, frames] or [frames, %v]", shp, NCtrlParams, NCtrlParams)
+	}
+	vals := make([]float32, NCtrlParams)
+	for f := 0; f < nfr; f++ {
+		for p := range vals {
+			vals[p] = float32(cell.FloatVal1D(p*pstr + f*fstr))
+		}
+		if err := vt.CtrlFromValues(vals, normalized); err != nil {
+			return err
+		}
+		if err := vt.Synthesize(resetFirst && f == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SynthFromTable synthesizes from the control parameters in the named column of each row of the table
+// in turn, as in SynthFromDataTable -- if resetFirst, the tract is reset before the first row only
+func (vt *VocalTract) SynthFromTable(dt *etable.Table, colNm string, normalized, resetFirst bool) error {
+	col := dt.ColByName(colNm)
+	if col == nil {
+		return fmt.Errorf("trm.SynthFromTable: column %v not found", colNm)
+	}
+	for row := 0; row < dt.Rows; row++ {
+		if err := vt.SynthFromDataTable(col, row, normalized, resetFirst && row == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadEnglishPhones loads the English phones table that is built into the package (EnglishPhones)
+func (vt *VocalTract) LoadEnglishPhones() error {
+	err := vt.PhoneTable.ReadCSV(strings.NewReader(EnglishPhones), '\t')
+	if err != nil {
+		return fmt.Errorf("trm.LoadEnglishPhones: %v", err)
+	}
+	err = vt.CheckPhoneTable()
+	if err != nil {
+		return err
+	}
+	return vt.Postures.ConfigFromTable(&vt.PhoneTable)
+}
+
+// OpenPhones loads the phones table from the given file (e.g., VocalTractEnglishPhones.dat),
+// overriding the built-in English phones -- the file must have the same columns as EnglishPhones
+func (vt *VocalTract) OpenPhones(fn gi.FileName) error {
 	err := vt.PhoneTable.OpenCSV(fn, '\t')
 	if err != nil {
-		fmt.Printf("File not found or error opengin file: %s (%s)", fn, err)
-		return
+		return fmt.Errorf("trm.OpenPhones: error opening file: %s (%v)", fn, err)
+	}
+	err = vt.CheckPhoneTable()
+	if err != nil {
+		return err
+	}
+	return vt.Postures.ConfigFromTable(&vt.PhoneTable)
+}
+
+// CheckPhoneTable returns an error if the PhoneTable is empty or does not have the
+// phone, duration, transition and phone_data columns, with NCtrlParams values of phone_data per phone
+func (vt *VocalTract) CheckPhoneTable() error {
+	dt := &vt.PhoneTable
+	if dt.Rows == 0 {
+		return errors.New("trm.CheckPhoneTable: phone table is empty")
 	}
+	for _, cn := range []string{"phone", "duration", "transition", "phone_data"} {
+		if dt.ColByName(cn) == nil {
+			return fmt.Errorf("trm.CheckPhoneTable: phone table is missing column: %s", cn)
+		}
+	}
+	pd := dt.ColByName("phone_data")
+	if pd.Len() != dt.Rows*NCtrlParams {
+		return fmt.Errorf("trm.CheckPhoneTable: phone_data column must have %d values per phone, has shape: %v", NCtrlParams, pd.Shapes())
+	}
+	return nil
 }
 
 // LoadEnglishDict loads the English dictionary of words composed of phones and transitions
-func (vt *VocalTract) LoadEnglishDict() {
-	fn := gi.FileName("VocalTractEnglishDict.dtbl")
+// that is built into the package (EnglishDict)
+func (vt *VocalTract) LoadEnglishDict() error {
+	err := vt.DictTable.ReadCSV(strings.NewReader(EnglishDict), '\t')
+	if err != nil {
+		return fmt.Errorf("trm.LoadEnglishDict: %v", err)
+	}
+	return vt.CheckDictTable()
+}
+
+// OpenDict loads the dictionary from the given file (e.g., VocalTractEnglishDict.dtbl),
+// overriding the built-in English dictionary -- the file must have word and phones columns
+func (vt *VocalTract) OpenDict(fn gi.FileName) error {
 	err := vt.DictTable.OpenCSV(fn, '\t')
 	if err != nil {
-		fmt.Printf("File not found or error opengin file: %s (%s)", fn, err)
-		return
+		return fmt.Errorf("trm.OpenDict: error opening file: %s (%v)", fn, err)
 	}
+	return vt.CheckDictTable()
 }
 
-// SynthPhone
-func (vt *VocalTract) SynthPhone(phon string, stress, doubleStress, syllable, reset bool) bool {
-	if vt.PhoneTable.Rows == 0 {
-		vt.LoadEnglishPhones()
+// CheckDictTable returns an error if the DictTable is empty or does not have the word and phones columns
+func (vt *VocalTract) CheckDictTable() error {
+	dt := &vt.DictTable
+	if dt.Rows == 0 {
+		return errors.New("trm.CheckDictTable: dictionary table is empty")
 	}
-	if stress {
-		phon = phon + "'"
+	for _, cn := range []string{"word", "phones"} {
+		if dt.ColByName(cn) == nil {
+			return fmt.Errorf("trm.CheckDictTable: dictionary table is missing column: %s", cn)
+		}
 	}
-	pcol := vt.PhoneTable.ColByName("phone")
-	idx := -1
-	for i := 0; i < pcol.Len(); i++ {
-		if pcol.StringVal1D(i) == phon {
-			idx = i
-			break
+	return nil
+}
+
+// InitPhones loads the English phones if no phones have been loaded, and sets the default
+// Rules if there are none
+func (vt *VocalTract) InitPhones() error {
+	if vt.PhoneTable.Rows == 0 || len(vt.Postures.Postures) == 0 {
+		err := vt.LoadEnglishPhones()
+		if err != nil {
+			return err
 		}
 	}
-	if idx < 0 {
-		return false
+	if len(vt.Rules.Rules) == 0 {
+		vt.Rules.Defaults()
 	}
+	return nil
+}
 
-	dcol := vt.PhoneTable.ColByName("duration")
-	dur := dcol.FloatVal1D(idx)
-	tcol := vt.PhoneTable.ColByName("transition")
-	trans := tcol.FloatVal1D(idx)
-	totalTime := (dur + trans) * 1.5
+// NewPhone returns a Phone for the given phone name and stress marks, with its timing
+// from the posture -- false if the phone is not found
+func (vt *VocalTract) NewPhone(phon string, stress, doubleStress, syllable bool) (Phone, bool) {
+	ps := vt.Postures.Posture(phon, stress || doubleStress)
+	if ps == nil {
+		return Phone{}, false
+	}
+	ph := Phone{Posture: ps, Stress: stress, DblStress: doubleStress, SylEnd: syllable}
+	ph.Duration = ps.Duration
+	ph.Transition = ps.Transition
+	if doubleStress {
+		ph.Duration *= vt.Rules.DblStressDur
+	}
+	return ph, true
+}
 
-	nReps := math.Ceil(totalTime / float64(vt.Duration))
-	nReps = math.Max(nReps, 1.0)
+// SynthSeq synthesizes the sequence of phones, with the control trajectory generated by the Rules,
+// starting from the current control state -- if Prosody is on, its rhythm and intonation are applied,
+// with tgs the type of each tone group in the sequence (see Prosody.Contour) -- returns the
+// InitPhones, SynthReset, Trajectory or Synthesize error, if any
+func (vt *VocalTract) SynthSeq(seq []Phone, tgs []ToneGroups, resetFirst bool) error {
+	if len(seq) == 0 {
+		return errors.New("trm.SynthSeq: no phones to synthesize")
+	}
+	if err := vt.InitPhones(); err != nil {
+		return err
+	}
+	if resetFirst {
+		if err := vt.SynthReset(true); err != nil {
+			return err
+		}
+	}
+	if vt.Prosody.On {
+		vt.Prosody.Rhythm(seq)
+	}
+	Timing(seq)
+	// the phone targets are relative to the reference pitch of the voice
+	start := vt.CurControl
+	start.GlotPitch -= vt.PitchRef()
+	traj, err := vt.Rules.Trajectory(seq, &start, vt.Duration)
+	if err != nil {
+		return err
+	}
+	if vt.Prosody.On {
+		vt.Prosody.Contour(seq, tgs, traj, vt.Duration)
+	}
+	for i := range traj {
+		vt.Recorder.Phone = seq[phoneAt(seq, float32(i)*vt.Duration)].Posture.Name
+		vt.CurControl.SetFromParams(&traj[i])
+		vt.CurControl.GlotPitch += vt.PitchRef()
+		vt.CurControl.Clamp(&vt.CtrlMin, &vt.CtrlMax) // the contours can overshoot the ranges
+		if err := vt.Synthesize(false); err != nil {
+			vt.Recorder.Phone = ""
+			return err
+		}
+	}
+	vt.Recorder.Phone = ""
+	return nil
+}
 
-	vt.ControlFromDataTable(vt.PhoneTable.ColByName("phone_data"), idx, false)
-	// todo: syllable, double_stress, qsss other params??
-	// fmt.Println("saying:", phon, "dur:", String(tot_time), "n_reps:", String(n_reps),
-	//              "start pos:", String(outputData_.size()));
-	if reset {
-		vt.SynthReset(true)
+// SynthPhone synthesizes one phone, transitioning from the current control state
+func (vt *VocalTract) SynthPhone(phon string, stress, doubleStress, syllable, reset bool) error {
+	if err := vt.InitPhones(); err != nil {
+		return err
 	}
-	for i := 0; i < int(nReps); i++ {
-		vt.Synthesize(false)
+	ph, ok := vt.NewPhone(phon, stress, doubleStress, syllable)
+	if !ok {
+		return fmt.Errorf("trm.SynthPhone: phone not found: %s", phon)
 	}
-	return true
+	return vt.SynthSeq([]Phone{ph}, nil, reset)
 }
 
-// SynthPhones
-func (vt *VocalTract) SynthPhones(phones string, resetFirst, play bool) bool {
+// PhoneSeq appends the phones in the string to the sequence: phones are separated by _,
+// syllables by . and the end of the phones is marked by % -- ' marks the following phone as
+// stressed, and " as double stressed -- false if a phone is not found
+func (vt *VocalTract) PhoneSeq(phones string, seq []Phone) ([]Phone, bool) {
+	if vt.InitPhones() != nil {
+		return seq, false
+	}
 	var phone string
 	stress := false
 	doubleStress := false
-	syllable := false
-	first := true
+	ok := true
+
+	add := func(syllable bool) {
+		if phone != "" {
+			ph, has := vt.NewPhone(phone, stress, doubleStress, syllable)
+			if has {
+				seq = append(seq, ph)
+			} else {
+				ok = false
+			}
+		}
+		stress = false
+		doubleStress = false
+		phone = ""
+	}
 
 	for _, r := range phones {
 		c := string(r)
@@ -652,51 +1007,63 @@ func (vt *VocalTract) SynthPhones(phones string, resetFirst, play bool) bool {
 			continue
 		}
 		if c == "%" {
-			vt.SynthPhone(phone, stress, doubleStress, syllable, resetFirst && first)
-			phone = ""
-			first = false
 			break // done
 		}
 		if c == "." { // syllable
-			syllable = true
-			vt.SynthPhone(phone, stress, doubleStress, syllable, resetFirst && first)
-			stress = false
-			doubleStress = false
-			syllable = false
-			phone = ""
-			first = false
+			add(true)
 			continue
 		}
 		if c == "_" { // reg separator
-			vt.SynthPhone(phone, stress, doubleStress, syllable, resetFirst && first)
-			stress = false
-			doubleStress = false
-			syllable = false
-			phone = ""
-			first = false
+			add(false)
 			continue
 		}
 		phone += c
 	}
-	if len(phone) > 0 {
-		vt.SynthPhone(phone, stress, doubleStress, syllable, resetFirst && first)
-	}
+	add(true)
+	return seq, ok
+}
 
+// SynthPhones synthesizes the phones in the string, as a sequence -- see PhoneSeq for the format
+func (vt *VocalTract) SynthPhones(phones string, resetFirst, play bool) error {
+	if err := vt.InitPhones(); err != nil {
+		return err
+	}
+	seq, ok := vt.PhoneSeq(phones, nil)
+	if !ok {
+		return fmt.Errorf("trm.SynthPhones: phone not found in: %s", phones)
+	}
+	err := vt.SynthSeq(seq, nil, resetFirst)
 	if play {
 		PlaySound()
 	}
-	return true
+	return err
+}
+
+// WordPhones returns the phones for the word from the DictTable, also trying the word in lower case --
+// words not in the dictionary are converted using the LetterToSound rules -- false if the word has no phones
+func (vt *VocalTract) WordPhones(word string) (string, bool) {
+	if phones, ok := vt.DictPhones(word); ok {
+		return phones, true
+	}
+	if lw := strings.ToLower(word); lw != word {
+		if phones, ok := vt.DictPhones(lw); ok {
+			return phones, true
+		}
+	}
+	phones := LetterToSound(word)
+	return phones, phones != ""
 }
 
-// SynthWord
-func (vt *VocalTract) SynthWord(word string, resetFirst bool, play bool) bool {
+// DictPhones returns the phones for the word from the DictTable -- false if not found
+func (vt *VocalTract) DictPhones(word string) (string, bool) {
 	if vt.DictTable.Rows == 0 {
-		vt.LoadEnglishDict()
+		if vt.LoadEnglishDict() != nil {
+			return "", false
+		}
 	}
 	col := vt.DictTable.ColByName("word")
 	if col == nil {
-		fmt.Printf("Column name 'word' not found")
-		return false
+		return "", false
 	}
 
 	var idx = -1
@@ -706,34 +1073,88 @@ func (vt *VocalTract) SynthWord(word string, resetFirst bool, play bool) bool {
 		}
 	}
 	if idx == -1 {
-		return false
+		return "", false
 	}
 	col = vt.DictTable.ColByName("phones")
 	if col == nil {
-		fmt.Printf("Column name 'phones' not found")
-		return false
+		return "", false
+	}
+	return col.StringVal1D(idx), true
+}
+
+// SynthWord synthesizes the phones of the word from the DictTable, or from the LetterToSound rules
+// if the word is not in the dictionary
+func (vt *VocalTract) SynthWord(word string, resetFirst bool, play bool) error {
+	phones, ok := vt.WordPhones(word)
+	if !ok {
+		return fmt.Errorf("trm.SynthWord: no phones for word: %s", word)
 	}
-	phones := col.StringVal1D(idx)
 	return vt.SynthPhones(phones, resetFirst, play)
 }
 
-// SynthWords
-func (vt *VocalTract) SynthWords(ws string, resetFirst bool, play bool) bool {
-	words := strings.Split(ws, " ")
-	rval := true
+// SynthText synthesizes English text, which is converted to words by NormalizeText
+func (vt *VocalTract) SynthText(text string, resetFirst bool, play bool) error {
+	return vt.SynthWords(NormalizeText(text), resetFirst, play)
+}
+
+// SynthWords synthesizes the space-separated words as one sequence of phones, with silence (#)
+// between the words, so the articulation is continuous across words -- punctuation at the end
+// of a word ends a tone group: ? for a Question, , for a Continuation, and . ! ; : for a Statement,
+// and is followed by a pause of Prosody.PhrasePause or SentPause
+func (vt *VocalTract) SynthWords(ws string, resetFirst bool, play bool) error {
+	if err := vt.InitPhones(); err != nil {
+		return err
+	}
+	words := strings.Fields(ws)
+	var seq []Phone
+	var tgs []ToneGroups
+	var err error
 	for i := 0; i < len(words); i++ {
-		rval := vt.SynthWord(words[i], (resetFirst && (i == 0)), false)
-		if !rval {
-			break
+		word := strings.TrimRight(words[i], ",.?!;:")
+		punct := words[i][len(word):]
+		if word != "" {
+			phones, ok := vt.WordPhones(word)
+			if !ok {
+				err = fmt.Errorf("trm.SynthWords: no phones for word: %s", word)
+				break
+			}
+			seq, ok = vt.PhoneSeq(phones, seq)
+			if !ok {
+				err = fmt.Errorf("trm.SynthWords: phone not found in: %s for word: %s", phones, word)
+				break
+			}
+		}
+		if punct != "" && len(seq) > 0 {
+			tg := ToneGroups(Statement)
+			switch {
+			case strings.Contains(punct, "?"):
+				tg = Question
+			case strings.Contains(punct, ","):
+				tg = Continuation
+			}
+			seq[len(seq)-1].GroupEnd = true
+			tgs = append(tgs, tg)
 		}
 		if i < len(words)-1 {
-			vt.SynthPhone("#", false, false, false, false)
+			if ph, ok := vt.NewPhone("#", false, false, false); ok {
+				pause := vt.Prosody.PhrasePause
+				if strings.ContainsAny(punct, ".!?") {
+					pause = vt.Prosody.SentPause
+				}
+				if punct != "" && pause > ph.Duration {
+					ph.Duration = pause
+				}
+				seq = append(seq, ph)
+			}
 		}
 	}
+	if err == nil {
+		err = vt.SynthSeq(seq, tgs, resetFirst)
+	}
 	if play {
 		PlaySound()
 	}
-	return rval
+	return err
 }
 
 // Initialize
@@ -743,6 +1164,11 @@ func (vt *VocalTract) Initialize() {
 	vt.Duration = 25.0
 	vt.ControlRate = 0.0
 	vt.DeltaMax.DefaultMaxDeltas()
+	vt.CtrlMin.DefaultMins()
+	vt.CtrlMax.DefaultMaxs()
+	vt.Prosody.Defaults()
+	vt.Recorder.Defaults()
+	vt.Spatializer.Defaults()
 	// outputData_.reserve(OUTPUT_VECTOR_RESERVE);
 }
 
@@ -760,6 +1186,9 @@ func (vt *VocalTract) Reset() {
 	for i := 0; i < OroPharynxCoefCount; i++ {
 		vt.OropharynxCoefs[i] = 0.0
 	}
+	if vt.Config.NSects > 0 {
+		vt.Waveguide.Init(vt.Config.NSects)
+	}
 
 	for i := 0; i < NasalTractSectCount; i++ {
 		for j := 0; j < 2; j++ {
@@ -784,6 +1213,7 @@ func (vt *VocalTract) Reset() {
 	vt.BreathinessFactor = 0.0
 	vt.PrevGlotAmplitude = -1.0
 	vt.OutputData = vt.OutputData[:0]
+	vt.Recorder.Reset()
 
 	vt.SampleRateConverter.Reset()
 	vt.MouthRadiationFilter.Reset()
@@ -802,32 +1232,39 @@ func SpeedOfSound(temp float32) float32 {
 	return 331.4 + (0.6 * temp)
 }
 
-//InitializeSynthesizer initializes all variables so that the synthesis can be run
-func (vt *VocalTract) InitializeSynthesizer() {
-	var nyquist float32
+// InitializeSynthesizer initializes all variables so that the synthesis can be run
+// -- returns ErrTractLength if the Voice TractLength is not positive
+func (vt *VocalTract) InitializeSynthesizer() error {
+	if vt.Voice.TractLength <= 0.0 {
+		vt.ControlPeriod = 0
+		return ErrTractLength
+	}
 
 	// calculate the sample rate, based on nominal tube length and speed of sound
-	if vt.Voice.TractLength > 0.0 {
-		c := SpeedOfSound(vt.Config.Temp)
-		vt.ControlPeriod = int(math.Round(float64(c*OroPharynxSectCount*100.0) / float64(vt.Voice.TractLength*vt.ControlRate)))
-		vt.SampleRate = int(vt.ControlRate * float32(vt.ControlPeriod))
-		vt.ActualTubeLength = float32(c*OroPharynxSectCount*100.0) / float32(vt.SampleRate)
-		nyquist = float32(vt.SampleRate) / 2.0
-		return
-	} else {
-		nyquist = 1.0
-		fmt.Println("Illegal tube length")
-	}
+	c := SpeedOfSound(vt.Config.Temp)
+	nSects := float32(vt.SectCount())
+	vt.ControlPeriod = int(math.Round(float64(c*nSects*100.0) / float64(vt.Voice.TractLength*vt.ControlRate)))
+	vt.SampleRate = int(vt.ControlRate * float32(vt.ControlPeriod))
+	vt.ActualTubeLength = float32(c*nSects*100.0) / float32(vt.SampleRate)
+	nyquist := float32(vt.SampleRate) / 2.0
 
 	vt.BreathinessFactor = vt.Voice.Breathiness / 100.0
 	vt.CrossmixFactor = 1.0 / Amplitude(vt.Config.MixOff)
 	vt.DampingFactor = (1.0 - (vt.Config.Loss / 100.0))
 
 	// initialize the wave table
-	vt.Voice.SetDefault(Female)
 	gs := WavetableGlottalSource{}
 	vt.GlottalSource = gs
-	vt.GlottalSource.Init(GlottalSourcePulse, float32(vt.SampleRate), vt.Voice.GlotPulseRise, vt.Voice.GlotPulseFallMin, vt.Voice.GlotPulseFallMax)
+	vt.GlottalSource.Oversample = vt.Config.Oversample
+	vt.GlottalSource.Rd = vt.Config.Rd
+	vt.GlottalSource.OpenQuot = vt.Config.OpenQuot
+	vt.GlottalSource.Tilt = vt.Config.Tilt
+	vt.GlottalSource.Jitter = vt.Config.Jitter
+	vt.GlottalSource.Shimmer = vt.Config.Shimmer
+	err := vt.GlottalSource.Init(vt.Config.WaveForm, float32(vt.SampleRate), vt.Voice.GlotPulseRise, vt.Voice.GlotPulseFallMin, vt.Voice.GlotPulseFallMax)
+	if err != nil {
+		return fmt.Errorf("trm.InitializeSynthesizer: error initializing the oversampling filter: %v", err)
+	}
 	vt.GlottalSource.Reset()
 
 	mouthApertureCoef := (nyquist - vt.Config.MouthCoef) / nyquist
@@ -846,60 +1283,86 @@ func (vt *VocalTract) InitializeSynthesizer() {
 	vt.Throat.Init(float32(vt.SampleRate), vt.Config.ThroatCutoff, Amplitude(vt.Config.ThroatVol))
 	vt.Throat.Reset()
 
-	vt.SampleRateConverter.Init(vt.SampleRate, OutputRate, &vt.OutputData)
+	if vt.Config.OutputRate <= 0 {
+		vt.Config.OutputRate = DefaultOutputRate
+	}
+	vt.SampleRateConverter.Init(vt.SampleRate, vt.Config.OutputRate, &vt.OutputData)
 	vt.SampleRateConverter.Reset()
 	vt.OutputData = vt.OutputData[:0]
 
 	vt.BandpassFilter.Reset()
 	vt.NoiseFilter.Reset()
 	vt.NoiseSource.Reset()
+	return nil
 }
 
-func (vt *VocalTract) InitSynth() {
+// InitSynth resets and initializes the synthesizer, starting from the current control params
+func (vt *VocalTract) InitSynth() error {
 	vt.InitBuffer()
 	vt.Reset()
 	vt.ControlRate = 1.0 / (vt.Duration / 1000.0)
-	vt.InitializeSynthesizer()
+	err := vt.InitializeSynthesizer()
 	vt.PrevControl.SetFromParams(&vt.CurControl)
 	vt.CurrentData.SetFromParams(&vt.CurControl)
+	return err
 }
 
-// InitBuffer
+// InitBuffer initializes the audio buffer for one control period of output at the output sample rate
 func (vt *VocalTract) InitBuffer() {
-	frames := (vt.Duration / 1000.0) * float32(vt.SampleRate)
+	frames := (vt.Duration / 1000.0) * float32(vt.Config.OutputRate)
 	format := &audio.Format{
 		NumChannels: 1,
-		SampleRate:  vt.SampleRate,
+		SampleRate:  vt.Config.OutputRate,
 	}
 	vt.AudioBuf.Buf = &audio.IntBuffer{Data: make([]int, int(frames)), Format: format, SourceBitDepth: 16}
 }
 
 // SynthReset
-func (vt *VocalTract) SynthReset(initBuffer bool) {
-	vt.InitSynth()
+func (vt *VocalTract) SynthReset(initBuffer bool) error {
+	err := vt.InitSynth()
 	if initBuffer {
 		vt.InitBuffer()
 	}
+	return err
 }
 
-// Synthesize
-func (vt *VocalTract) Synthesize(resetFirst bool) {
+// Synthesize synthesizes one control period (Duration), moving from the previous to the current
+// control params -- returns an error, without synthesizing, if the synthesizer cannot be initialized
+// or the current control params are out of range (see CheckCtrl)
+func (vt *VocalTract) Synthesize(resetFirst bool) error {
 	ctrlRate := 1.0 / (vt.Duration / 1000.0)
-	if ctrlRate != vt.ControlRate {
-		// todo:
-		//if ctrlRate != vt.ControlRate || !IsValid() {
-		vt.InitSynth()
+	if ctrlRate != vt.ControlRate || vt.ControlPeriod <= 0 {
+		if err := vt.InitSynth(); err != nil {
+			return err
+		}
 	} else if resetFirst {
-		vt.SynthReset(true)
+		if err := vt.SynthReset(true); err != nil {
+			return err
+		}
+	}
+	if err := vt.CheckCtrl(&vt.CurControl); err != nil {
+		return err
+	}
+
+	if vt.Morph.On {
+		vt.MorphStep()
 	}
 
-	controlFreq := 1.0 / vt.ControlPeriod
-	fmt.Printf("control period: %v, freq: %v", vt.ControlPeriod, controlFreq)
+	controlFreq := 1.0 / float32(vt.ControlPeriod)
 
-	vt.DeltaControl.ComputeDeltas(&vt.CurControl, &vt.PrevControl, &vt.DeltaMax, float32(controlFreq))
+	// linearly interpolate each control parameter from where we got to last time to the
+	// current targets over the control period
+	var deltaMax *VocalTractCtrl
+	if vt.RateLimit {
+		deltaMax = &vt.DeltaMax
+	}
+	vt.DeltaControl.ComputeDeltas(&vt.CurControl, &vt.PrevControl, deltaMax, controlFreq)
 
 	for j := 0; j < vt.ControlPeriod; j++ {
 		vt.SynthesizeImpl()
+		if vt.Recorder.On {
+			vt.Recorder.Record(vt)
+		}
 		vt.CurrentData.UpdateFromDeltas(&vt.DeltaControl)
 		vt.PrevControl.SetFromParams(&vt.CurrentData) // prev is where we actually got, not where we wanted to get..
 		// todo:
@@ -923,6 +1386,7 @@ func (vt *VocalTract) Synthesize(resetFirst bool) {
 		//#endif
 		// SigEmitUpdated();
 	}
+	return nil
 }
 
 // SynthesizeImpl
@@ -931,8 +1395,12 @@ func (vt *VocalTract) SynthesizeImpl() {
 	f0 := Frequency(vt.CurrentData.GlotPitch)
 	ax := Amplitude(vt.CurrentData.GlotVol)
 	ah1 := Amplitude(vt.CurrentData.AspVol)
-	vt.CalculateTubeCoefficients()
 	vt.SetFricationTaps()
+	if vt.Config.NSects > 0 {
+		vt.CalculateWaveguideCoefficients()
+	} else {
+		vt.CalculateTubeCoefficients()
+	}
 	vt.BandpassFilter.Update(float32(vt.SampleRate), vt.CurrentData.FricBw, vt.CurrentData.FricCf)
 
 	// do synthesis here
@@ -960,22 +1428,26 @@ func (vt *VocalTract) SynthesizeImpl() {
 		crossmix := ax * vt.CrossmixFactor
 		if crossmix >= 1.0 {
 			crossmix = 1.0
-			signal = (pulsedNoise * crossmix) + (lpNoise * (1.0 - crossmix))
-		} else {
-			signal = lpNoise
 		}
+		signal = (pulsedNoise * crossmix) + (lpNoise * (1.0 - crossmix))
+	} else {
+		signal = lpNoise
+	}
 
-		// put signal through vocal tract
+	// put signal through vocal tract
+	if vt.Config.NSects > 0 {
+		signal = vt.WaveguideUpdate(((pulse + (ah1 * signal)) * VtScale), vt.BandpassFilter.Filter(signal))
+	} else {
 		signal = vt.VocalTractUpdate(((pulse + (ah1 * signal)) * VtScale), vt.BandpassFilter.Filter(signal))
+	}
 
-		// put pulse through throat
-		signal += vt.Throat.Process(pulse * VtScale)
+	// put pulse through throat
+	signal += vt.Throat.Process(pulse * VtScale)
 
-		// output sample here
-		vt.SampleRateConverter.DataFill(signal)
+	// output sample here
+	vt.SampleRateConverter.DataFill(signal)
 
-		vt.PrevGlotAmplitude = ax
-	}
+	vt.PrevGlotAmplitude = ax
 }
 
 // InitializeNasalCavity
@@ -983,8 +1455,9 @@ func (vt *VocalTract) InitializeNasalCavity() {
 	var radA2, radB2 float32
 
 	// calculate coefficients for internal fixed sections of nasal cavity
+	// note: NoseRadii start at section 2 (section 1 is the velum)
 	for i, j := NasalTractSect2, NasalTractCoef2; i < NasalTractSect6; i, j = i+1, j+1 {
-		radA2 = vt.Voice.NoseRadiusVal(i)
+		radA2 = vt.Voice.NoseRadiusVal(i - 1)
 		radA2 *= radA2
 		radB2 = vt.Voice.NoseRadiusVal(i)
 		radB2 *= radB2
@@ -998,27 +1471,40 @@ func (vt *VocalTract) InitializeNasalCavity() {
 	vt.NasalCoefs[NasalTractCoef6] = (radA2 - radB2) / (radA2 + radB2)
 }
 
+// RadiusVal returns the radius of the oropharynx region at given *zero-based* index, from the current
+// control data for regions 2-8 and the voice Radius1 for region 1, scaled by the voice RadiusCoef
+func (vt *VocalTract) RadiusVal(idx int) float32 {
+	if idx <= 0 {
+		return vt.Voice.Radius1 * vt.Voice.RadiusCoef
+	}
+	r := vt.CurrentData.Radii[idx-1] * vt.Voice.RadiusCoef
+	if r < GsTrmTubeMinRadius {
+		r = GsTrmTubeMinRadius // a closed section would make the scattering coefficients undefined
+	}
+	return r
+}
+
 // CalculateTubeCoefficients
 func (vt *VocalTract) CalculateTubeCoefficients() {
 	var radA2, radB2 float32
 	// calculate coefficients for the oropharynx
 	for i := 0; i < OroPharynxRegCount-1; i++ {
-		radA2 = vt.CurrentData.RadiusVal(i)
+		radA2 = vt.RadiusVal(i)
 		radA2 *= radA2
-		radB2 = vt.CurrentData.RadiusVal(i + 1)
+		radB2 = vt.RadiusVal(i + 1)
 		radB2 *= radB2
 		vt.OropharynxCoefs[i] = (radA2 - radB2) / (radA2 + radB2)
 	}
 
 	// calculate the coefficient for the mouth aperture
-	radA2 = vt.CurrentData.RadiusVal(OroPharynxReg8)
+	radA2 = vt.RadiusVal(OroPharynxReg8)
 	radA2 *= radA2
 	radB2 = vt.Voice.ApertureRadius * vt.Voice.ApertureRadius
 	vt.OropharynxCoefs[OroPharynxCoef8] = (radA2 - radB2) / (radA2 + radB2)
 
 	// calculate alpha coefficients for 3-way junction
 	// note:  since junction is in middle of region 4, r0_2 = r1_2
-	r0_2 := vt.CurrentData.RadiusVal(OroPharynxReg4)
+	r0_2 := vt.RadiusVal(OroPharynxReg4)
 	r0_2 *= r0_2
 	r1_2 := r0_2
 	r2_2 := vt.CurrentData.Velum * vt.CurrentData.Velum
@@ -1029,7 +1515,7 @@ func (vt *VocalTract) CalculateTubeCoefficients() {
 
 	// and 1st nasal passage coefficient
 	radA2 = vt.CurrentData.Velum * vt.CurrentData.Velum
-	radB2 = vt.Voice.NoseRadiusVal(NasalTractSect2)
+	radB2 = vt.Voice.NoseRadiusVal(NasalTractSect2 - 1) // zero based, starting at section 2
 	radB2 *= radB2
 	vt.NasalCoefs[NasalTractCoef1] = (radA2 - radB2) / (radA2 + radB2)
 }
@@ -1038,12 +1524,12 @@ func (vt *VocalTract) CalculateTubeCoefficients() {
 func (vt *VocalTract) SetFricationTaps() {
 	fricationAmplitude := Amplitude(vt.CurrentData.FricVol)
 
-	integerPart := vt.CurrentData.FricVol
+	integerPart := int(vt.CurrentData.FricPos)
 	complement := vt.CurrentData.FricPos - float32(integerPart)
 	remainder := 1.0 - complement
 
 	for i := FricationInjCoef1; i < FricationInjCoefCount; i++ {
-		if i == int(integerPart) {
+		if i == integerPart {
 			vt.FricationTap[i] = remainder * fricationAmplitude
 			if (i + 1) < FricationInjCoefCount {
 				i += 1
@@ -1145,9 +1631,15 @@ func (vt *VocalTract) VocalTractUpdate(input, frication float32) float32 {
 	output := vt.MouthRadiationFilter.Filter((1.0 + vt.OropharynxCoefs[OroPharynxCoef8]) *
 		vt.Oropharynx[OroPharynxSect10][Top][vt.PrevPtr])
 
+	// return summed output from mouth and nose
+	return output + vt.NasalUpdate()
+}
+
+// NasalUpdate updates the pressure wave throughout the nasal cavity, from the velum, and returns the output from the nose
+func (vt *VocalTract) NasalUpdate() float32 {
 	//  update nasal cavity
 	for i, j := Velum, NasalTractCoef1; i < NasalTractCoef6; i, j = i+1, j+1 {
-		delta = vt.NasalCoefs[j] *
+		delta := vt.NasalCoefs[j] *
 			(vt.Nasal[i][Top][vt.PrevPtr] - vt.Nasal[i+1][Bottom][vt.PrevPtr])
 		vt.Nasal[i+1][Top][vt.CurPtr] =
 			(vt.Nasal[i][Top][vt.PrevPtr] + delta) * vt.DampingFactor
@@ -1160,11 +1652,8 @@ func (vt *VocalTract) VocalTractUpdate(input, frication float32) float32 {
 		vt.NasalReflectionFilter.Filter(vt.NasalCoefs[NasalTractCoef6]*vt.Nasal[NasalTractCoef6][Top][vt.PrevPtr])
 
 	// output from nose goes through a highpass filter
-	output += vt.MouthRadiationFilter.Filter((1.0 + vt.NasalCoefs[NasalTractCoef6]) *
+	return vt.NasalRadiationFilter.Filter((1.0 + vt.NasalCoefs[NasalTractCoef6]) *
 		vt.Nasal[NasalTractSect6][Top][vt.PrevPtr])
-
-	// return summed output from mouth and nose
-	return output
 }
 
 // CalculateMonoScale
@@ -1172,20 +1661,24 @@ func (vt *VocalTract) CalculateMonoScale() float32 {
 	return (OutputScale / (vt.SampleRateConverter.MaxSampleVal()) * Amplitude(vt.Volume))
 }
 
-// CalculateStereoScale
+// CalculateStereoScale calculates the scaling of the left and right channels, panned according to Balance
+// (-1 is left, 1 is right), such that the louder channel has a maximum of OutputScale at full Volume
 func (vt *VocalTract) CalculateStereoScale(leftScale,
 	rightScale *float32) {
 	*leftScale = (-((vt.Balance / 2.0) - 0.5))
-	*rightScale = (-((vt.Balance / 2.0) + 0.5))
+	*rightScale = ((vt.Balance / 2.0) + 0.5)
 
-	scale := leftScale
+	chanScale := *leftScale
 	if vt.Balance > 0.0 {
-		scale = rightScale
+		chanScale = *rightScale
+	}
+	newMax := (vt.SampleRateConverter.MaxSampleVal() * chanScale)
+	if newMax <= 0 {
+		return
 	}
-	newMax := (vt.SampleRateConverter.MaxSampleVal() * (*scale))
-	*scale = (OutputScale / (newMax * Amplitude(vt.Volume)))
-	*leftScale *= *scale
-	*rightScale *= *scale
+	scale := (OutputScale / newMax * Amplitude(vt.Volume))
+	*leftScale *= scale
+	*rightScale *= scale
 }
 
 // Amplitude  converts dB value to amplitude value
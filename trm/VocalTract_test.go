@@ -0,0 +1,232 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/emer/etable/etensor"
+)
+
+// The snapshot files in testdata hold the output of this package itself for fixed input, and only
+// detect unintended changes of the synthesis -- they are NOT reference output of the gnuspeech C++
+// TRM, and the comparison against that remains to be done.  Regenerate them, after an intended
+// change of the output, by running go test -run Snapshot -update in this directory.
+var update = flag.Bool("update", false, "update the snapshot files in testdata")
+
+// snapshotTol is the tolerance of the comparison with the snapshot files, relative to the max absolute value
+const snapshotTol = 1e-3
+
+// checkSnapshot compares out with the values in testdata/name.snapshot, or writes them if -update
+func checkSnapshot(t *testing.T, name string, out []float32) {
+	t.Helper()
+	fn := filepath.Join("testdata", name+".snapshot")
+	if *update {
+		f, err := os.Create(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := bufio.NewWriter(f)
+		for _, v := range out {
+			fmt.Fprintf(w, "%.6g\n", v)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		t.Fatalf("%v -- run go test -run Snapshot -update to create it", err)
+	}
+	defer f.Close()
+	var snap []float64
+	max := 0.0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		v, err := strconv.ParseFloat(sc.Text(), 64)
+		if err != nil {
+			t.Fatalf("%s line %d: %v", fn, len(snap)+1, err)
+		}
+		snap = append(snap, v)
+		max = math.Max(max, math.Abs(v))
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(snap) {
+		t.Fatalf("%s: %d samples, snapshot %d", name, len(out), len(snap))
+	}
+	tol := snapshotTol * max
+	for i, v := range out {
+		if math.Abs(float64(v)-snap[i]) > tol {
+			t.Fatalf("%s: sample %d = %v, snapshot %v", name, i, v, snap[i])
+		}
+	}
+}
+
+// newTestVocalTract returns an initialized VocalTract with the default Male voice
+func newTestVocalTract(t *testing.T) *VocalTract {
+	t.Helper()
+	vt := &VocalTract{}
+	if err := vt.Init(); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	vt.Initialize()
+	return vt
+}
+
+func TestInitConfig(t *testing.T) {
+	var def VocalTractConfig
+	def.Defaults()
+	vt := newTestVocalTract(t)
+	if vt.Config != def {
+		t.Errorf("Init of an unset Config = %+v, want the Defaults %+v", vt.Config, def)
+	}
+
+	// values set after Defaults are kept, including zero and false ones
+	vt = &VocalTract{}
+	vt.Config.Defaults()
+	vt.Config.Temp = 0
+	vt.Config.Loss = 0
+	vt.Config.NoiseMod = false
+	vt.Config.OutputRate = 16000
+	want := vt.Config
+	if err := vt.Init(); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	if vt.Config != want {
+		t.Errorf("Init changed the Config to %+v, want %+v", vt.Config, want)
+	}
+}
+
+func TestInitVoice(t *testing.T) {
+	var male, female VoiceParams
+	male.SetDefault(Male)
+	female.Init()
+	if vt := newTestVocalTract(t); vt.Voice != male {
+		t.Errorf("Init of an unset Voice = %+v, want the Male voice %+v", vt.Voice, male)
+	}
+	vt := &VocalTract{}
+	vt.Voice.Init()
+	if err := vt.Init(); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	if vt.Voice != female {
+		t.Errorf("Init after Voice.Init = %+v, want the Female voice %+v", vt.Voice, female)
+	}
+}
+
+func TestSnapshotCtrl(t *testing.T) {
+	vt := newTestVocalTract(t)
+	vt.CurControl.Init()
+	// a voiced vowel with aspiration and frication, to run the noise filters as well
+	vt.CurControl.GlotPitch = 0
+	vt.CurControl.GlotVol = 60
+	vt.CurControl.AspVol = 5
+	vt.CurControl.FricVol = 20
+	vt.CurControl.Radii = [7]float32{0.8, 0.8, 0.4, 1.6, 1.9, 1.2, 0.4}
+	for i := 0; i < 8; i++ {
+		if err := vt.Synthesize(false); err != nil {
+			t.Fatalf("Synthesize error: %v", err)
+		}
+	}
+	checkSnapshot(t, "ctrl", vt.OutputData)
+}
+
+// testFrames returns the values of n frames of a voiced vowel, with the pitch of frame f set to -f
+func testFrames(n int) [][NCtrlParams]float32 {
+	var ctrl VocalTractCtrl
+	ctrl.Init()
+	ctrl.GlotVol = 60
+	frs := make([][NCtrlParams]float32, n)
+	for f := range frs {
+		ctrl.GlotPitch = -float32(f)
+		for p := 0; p < NCtrlParams; p++ {
+			frs[f][p] = *ctrl.ParamVal(p)
+		}
+	}
+	return frs
+}
+
+func TestSynthFromDataTable(t *testing.T) {
+	const nfr = 3
+	frs := testFrames(nfr)
+	pf := etensor.NewFloat32([]int{2, NCtrlParams, nfr}, nil, nil) // [params, frames] in row 1
+	fp := etensor.NewFloat32([]int{2, nfr, NCtrlParams}, nil, nil) // [frames, params] in row 1
+	for f := range frs {
+		for p, v := range frs[f] {
+			pf.Set([]int{1, p, f}, v)
+			fp.Set([]int{1, f, p}, v)
+		}
+	}
+	var outs [][]float32
+	for _, col := range []*etensor.Float32{pf, fp} {
+		vt := newTestVocalTract(t)
+		if err := vt.SynthFromDataTable(col, 1, false, true); err != nil {
+			t.Fatalf("cell shape %v: SynthFromDataTable error: %v", col.Shapes()[1:], err)
+		}
+		if vt.CurControl.GlotPitch != -(nfr-1) || vt.CurControl.GlotVol != 60 {
+			t.Errorf("cell shape %v: pitch and volume after the last frame = %v, %v, want %v, 60", col.Shapes()[1:], vt.CurControl.GlotPitch, vt.CurControl.GlotVol, -(nfr - 1))
+		}
+		outs = append(outs, vt.OutputData)
+	}
+	if len(outs[0]) != len(outs[1]) {
+		t.Fatalf("[params, frames] gives %d samples, [frames, params] %d", len(outs[0]), len(outs[1]))
+	}
+	for i, v := range outs[0] {
+		if v != outs[1][i] {
+			t.Fatalf("sample %d of [params, frames] = %v, of [frames, params] = %v", i, v, outs[1][i])
+		}
+	}
+
+	// a square cell is [params, frames], with the pitch of frame f = -f
+	sq := etensor.NewFloat32([]int{1, NCtrlParams, NCtrlParams}, nil, nil)
+	sqfr := testFrames(NCtrlParams)
+	for f := range sqfr {
+		for p, v := range sqfr[f] {
+			sq.Set([]int{0, p, f}, v)
+		}
+	}
+	vt := newTestVocalTract(t)
+	if err := vt.SynthFromDataTable(sq, 0, false, true); err != nil {
+		t.Fatalf("square cell: SynthFromDataTable error: %v", err)
+	}
+	if vt.CurControl.GlotPitch != -(NCtrlParams - 1) {
+		t.Errorf("square cell: pitch after the last frame = %v, want %v", vt.CurControl.GlotPitch, -(NCtrlParams - 1))
+	}
+
+	// a vector is one frame
+	vec := etensor.NewFloat32([]int{1, NCtrlParams}, nil, nil)
+	copy(vec.Values, frs[2][:])
+	vt = newTestVocalTract(t)
+	if err := vt.SynthFromDataTable(vec, 0, false, true); err != nil {
+		t.Fatalf("vector cell: SynthFromDataTable error: %v", err)
+	}
+	if vt.CurControl.GlotPitch != -2 {
+		t.Errorf("vector cell: pitch = %v, want -2", vt.CurControl.GlotPitch)
+	}
+
+	bad := etensor.NewFloat32([]int{1, 4, 5}, nil, nil)
+	if err := vt.SynthFromDataTable(bad, 0, false, true); err == nil {
+		t.Errorf("cell shape [4 5]: no error")
+	}
+	if err := vt.SynthFromDataTable(pf, 2, false, true); err == nil {
+		t.Errorf("row out of range: no error")
+	}
+	if err := vt.SynthFromDataTable(nil, 0, false, true); err == nil {
+		t.Errorf("nil column: no error")
+	}
+}
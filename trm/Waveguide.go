@@ -0,0 +1,304 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// RegionPos are the positions of the centers of the oropharynx regions along the tract, from the
+// glottis (0) to the lips (1), as in the 10 section model -- used to interpolate section radii from the region radii
+var RegionPos = [OroPharynxRegCount]float32{0.05, 0.15, 0.25, 0.4, 0.6, 0.75, 0.85, 0.95}
+
+// VelumPos is the position of the 3-way junction with the nasal tract along the tract, as in the 10 section model
+const VelumPos = 0.4
+
+// FricPosOffset is the section (in the 10 section model) at which frication position 0 is injected
+const FricPosOffset = 2
+
+// MinWaveguideSects is the minimum number of sections of the Waveguide
+const MinWaveguideSects = 4
+
+// Waveguide is a generalized oropharynx of any number of sections of equal length, each a one sample
+// delay in each direction, with scattering junctions between sections, the 3-way junction with the
+// nasal tract at VelumPos, and frication injected according to FricPos along the tract.
+// Increasing the number of sections for the same tract length increases the internal sample rate proportionally,
+// so the nasal tract has its own sections of the same length, scaled from the 6 of the 10 section model
+// to keep its length: the first VelumSects have the velum radius and the rest the interpolated nose radii of the voice.
+// The radius of each section is interpolated from the region radii of the control params, unless
+// SectRadii are set directly (see VocalTract.SetSectRadii)
+type Waveguide struct {
+	NSects       int             // number of sections
+	VelumSect    int             // section at the start of which the nasal tract branches off
+	UseSectRadii bool            // use the SectRadii as set, instead of interpolating them from the region radii
+	SectRadii    []float32       // radius of each section in cm
+	Coefs        []float32       // scattering junction coefficients between each section and the next, and (last) with the air at the mouth
+	FricTaps     []float32       // amplitude of frication injected into each section
+	Sects        [][2][2]float32 // pressure in each section: [Top, Bottom] x [current, previous] (by CurPtr and PrevPtr)
+	NasalSects   int             // number of sections of the nasal tract
+	VelumSects   int             // number of sections at the start of the nasal tract that have the velum radius
+	NasalRadii   []float32       // radius of each section of the nasal tract in cm
+	NasalCoefs   []float32       // scattering junction coefficients between each nasal section and the next, and (last) with the air at the nostrils
+	Nasal        [][2][2]float32 // pressure in each section of the nasal tract, as in Sects
+}
+
+// WaveguideSectCount returns the number of sections of a Waveguide initialized for nSects sections: at least MinWaveguideSects
+func WaveguideSectCount(nSects int) int {
+	if nSects < MinWaveguideSects {
+		return MinWaveguideSects
+	}
+	return nSects
+}
+
+// Init allocates the waveguide for the given number of sections (at least MinWaveguideSects), and the nasal
+// tract for sections of the same length, and resets it
+func (wg *Waveguide) Init(nSects int) {
+	nSects = WaveguideSectCount(nSects)
+	if wg.NSects != nSects {
+		wg.NSects = nSects
+		wg.SectRadii = make([]float32, nSects)
+		wg.Coefs = make([]float32, nSects)
+		wg.FricTaps = make([]float32, nSects)
+		wg.Sects = make([][2][2]float32, nSects)
+		for i := range wg.SectRadii {
+			wg.SectRadii[i] = 1
+		}
+		nn := int(math.Round(NasalTractSectCount * float64(nSects) / OroPharynxSectCount))
+		if nn < 2 {
+			nn = 2
+		}
+		wg.NasalSects = nn
+		wg.VelumSects = int(math.Round(float64(nn) / NasalTractSectCount))
+		if wg.VelumSects < 1 {
+			wg.VelumSects = 1
+		}
+		wg.NasalRadii = make([]float32, nn)
+		wg.NasalCoefs = make([]float32, nn)
+		wg.Nasal = make([][2][2]float32, nn)
+	}
+	wg.VelumSect = int(math.Round(VelumPos * float64(nSects)))
+	wg.Reset()
+}
+
+// Reset sets the pressures in all the sections, and those of the nasal tract, to 0
+func (wg *Waveguide) Reset() {
+	for i := range wg.Sects {
+		wg.Sects[i] = [2][2]float32{}
+	}
+	for i := range wg.Nasal {
+		wg.Nasal[i] = [2][2]float32{}
+	}
+}
+
+// SetNasalRadii sets the NasalRadii: the velum radius for the first VelumSects, and the nose radii of
+// the voice (scaled by its NoseRadiusCoef) for the rest, linearly interpolated at the center of each section
+func (wg *Waveguide) SetNasalRadii(velum float32, voice *VoiceParams) {
+	nNose := len(voice.NoseRadii)
+	nSects := wg.NasalSects - wg.VelumSects
+	for i := 0; i < wg.VelumSects; i++ {
+		wg.NasalRadii[i] = velum
+	}
+	for i := 0; i < nSects; i++ {
+		x := (float32(i)+0.5)*float32(nNose)/float32(nSects) - 0.5
+		if x < 0 {
+			x = 0
+		} else if x > float32(nNose-1) {
+			x = float32(nNose - 1)
+		}
+		r := int(x)
+		if r >= nNose-1 {
+			r = nNose - 2
+		}
+		t := x - float32(r)
+		wg.NasalRadii[wg.VelumSects+i] = voice.NoseRadiusVal(r) + t*(voice.NoseRadiusVal(r+1)-voice.NoseRadiusVal(r))
+	}
+}
+
+// InterpRadii sets the SectRadii by linear interpolation of the region radii, given in
+// OroPharynxRegions order, at the center of each section
+func (wg *Waveguide) InterpRadii(regRadii *[OroPharynxRegCount]float32) {
+	for i := range wg.SectRadii {
+		x := (float32(i) + 0.5) / float32(wg.NSects)
+		r := 1
+		for r < OroPharynxRegCount-1 && RegionPos[r] < x {
+			r++
+		}
+		p0, p1 := RegionPos[r-1], RegionPos[r]
+		t := (x - p0) / (p1 - p0)
+		if t < 0 {
+			t = 0
+		} else if t > 1 {
+			t = 1
+		}
+		wg.SectRadii[i] = regRadii[r-1] + t*(regRadii[r]-regRadii[r-1])
+	}
+}
+
+// FricSect returns the section into which the given frication tap (FricationInjCoefs) is injected
+func (wg *Waveguide) FricSect(tap int) int {
+	s := int(math.Round(float64(tap+FricPosOffset) * float64(wg.NSects) / OroPharynxSectCount))
+	if s < 1 {
+		s = 1
+	} else if s > wg.NSects-1 {
+		s = wg.NSects - 1
+	}
+	return s
+}
+
+// SetSectRadii sets the radius of each section of the Waveguide directly, instead of interpolating
+// them from the region radii of the control params -- the radii take effect immediately, and
+// remain in effect until Waveguide.UseSectRadii is turned off -- radii below GsTrmTubeMinRadius
+// (or not a number) are set to it, as a closed section would make the coefficients undefined --
+// returns an error if the Waveguide is not in use (Config.NSects is 0) or there is not one radius per section
+func (vt *VocalTract) SetSectRadii(radii []float32) error {
+	if vt.Config.NSects <= 0 {
+		return errors.New("trm.SetSectRadii: section radii can only be set for the Waveguide, with Config.NSects > 0")
+	}
+	if len(radii) != vt.Waveguide.NSects {
+		return fmt.Errorf("trm.SetSectRadii: %d radii given for %d sections", len(radii), vt.Waveguide.NSects)
+	}
+	for i, r := range radii {
+		if !(r >= GsTrmTubeMinRadius) { // also catches NaN
+			r = GsTrmTubeMinRadius
+		}
+		vt.Waveguide.SectRadii[i] = r
+	}
+	vt.Waveguide.UseSectRadii = true
+	return nil
+}
+
+// SectCount returns the number of sections of the oropharynx: that of the Waveguide for Config.NSects
+// (see WaveguideSectCount), or OroPharynxSectCount
+func (vt *VocalTract) SectCount() int {
+	if vt.Config.NSects > 0 {
+		return WaveguideSectCount(vt.Config.NSects)
+	}
+	return OroPharynxSectCount
+}
+
+// TractCoefs returns the scattering junction coefficients of the oropharynx and the nasal tract: those
+// of the Waveguide if Config.NSects > 0, or else the OropharynxCoefs and NasalCoefs of the 10 section model
+func (vt *VocalTract) TractCoefs() (oro, nasal []float32) {
+	if vt.Config.NSects > 0 {
+		return vt.Waveguide.Coefs, vt.Waveguide.NasalCoefs
+	}
+	return vt.OropharynxCoefs[:], vt.NasalCoefs[:]
+}
+
+// CalculateWaveguideCoefficients calculates the Waveguide scattering junction, 3-way junction, nasal
+// tract and frication coefficients from the current control data, the counterpart of
+// CalculateTubeCoefficients and SetFricationTaps for the 10 section model -- SetFricationTaps must be called first
+func (vt *VocalTract) CalculateWaveguideCoefficients() {
+	wg := &vt.Waveguide
+	if !wg.UseSectRadii {
+		var regRadii [OroPharynxRegCount]float32
+		for i := range regRadii {
+			regRadii[i] = vt.RadiusVal(i)
+		}
+		wg.InterpRadii(&regRadii)
+	}
+	n := wg.NSects
+	for i := 0; i < n-1; i++ {
+		radA2 := wg.SectRadii[i] * wg.SectRadii[i]
+		radB2 := wg.SectRadii[i+1] * wg.SectRadii[i+1]
+		wg.Coefs[i] = (radA2 - radB2) / (radA2 + radB2)
+	}
+
+	// coefficient for the mouth aperture
+	radA2 := wg.SectRadii[n-1] * wg.SectRadii[n-1]
+	radB2 := vt.Voice.ApertureRadius * vt.Voice.ApertureRadius
+	wg.Coefs[n-1] = (radA2 - radB2) / (radA2 + radB2)
+
+	// alpha coefficients for the 3-way junction between VelumSect-1 and VelumSect
+	r0_2 := wg.SectRadii[wg.VelumSect-1] * wg.SectRadii[wg.VelumSect-1]
+	r1_2 := wg.SectRadii[wg.VelumSect] * wg.SectRadii[wg.VelumSect]
+	r2_2 := vt.CurrentData.Velum * vt.CurrentData.Velum
+	sum := 2.0 / (r0_2 + r1_2 + r2_2)
+	vt.Alpha[ThreeWayLeft] = sum * r0_2
+	vt.Alpha[ThreeWayRight] = sum * r1_2
+	vt.Alpha[ThreeWayUpper] = sum * r2_2
+
+	// nasal tract coefficients, and that of the nose aperture
+	wg.SetNasalRadii(vt.CurrentData.Velum, &vt.Voice)
+	nn := wg.NasalSects
+	for i := 0; i < nn-1; i++ {
+		radA2 := wg.NasalRadii[i] * wg.NasalRadii[i]
+		radB2 := wg.NasalRadii[i+1] * wg.NasalRadii[i+1]
+		wg.NasalCoefs[i] = (radA2 - radB2) / (radA2 + radB2)
+	}
+	radA2 = wg.NasalRadii[nn-1] * wg.NasalRadii[nn-1]
+	wg.NasalCoefs[nn-1] = (radA2 - radB2) / (radA2 + radB2)
+
+	for i := range wg.FricTaps {
+		wg.FricTaps[i] = 0
+	}
+	for i := FricationInjCoef1; i < FricationInjCoefCount; i++ {
+		wg.FricTaps[wg.FricSect(i)] += vt.FricationTap[i]
+	}
+}
+
+// WaveguideUpdate updates the pressure wave throughout the Waveguide and the nasal tract, and returns
+// the summed output of the oral and nasal cavities, the counterpart of VocalTractUpdate for the Waveguide
+func (vt *VocalTract) WaveguideUpdate(input, frication float32) float32 {
+	vt.CurPtr += 1
+	if vt.CurPtr > 1 {
+		vt.CurPtr = 0
+	}
+
+	vt.PrevPtr += 1
+	if vt.PrevPtr > 1 {
+		vt.PrevPtr = 0
+	}
+	wg := &vt.Waveguide
+	s := wg.Sects
+	n := wg.NSects
+	cur := vt.CurPtr
+	prv := vt.PrevPtr
+
+	// input to top of tube
+	s[0][Top][cur] = (s[0][Bottom][prv] * vt.DampingFactor) + input
+
+	// scattering junctions between sections, except at the velum
+	for i := 0; i < n-1; i++ {
+		if i+1 == wg.VelumSect {
+			continue
+		}
+		delta := wg.Coefs[i] * (s[i][Top][prv] - s[i+1][Bottom][prv])
+		s[i+1][Top][cur] = ((s[i][Top][prv] + delta) * vt.DampingFactor) + (wg.FricTaps[i+1] * frication)
+		s[i][Bottom][cur] = (s[i+1][Bottom][prv] + delta) * vt.DampingFactor
+	}
+
+	// 3-way junction between the sections at the velum and the nasal cavity
+	v := wg.VelumSect
+	ns := wg.Nasal
+	junctionPressure := (vt.Alpha[ThreeWayLeft] * s[v-1][Top][prv]) +
+		(vt.Alpha[ThreeWayRight] * s[v][Bottom][prv]) +
+		(vt.Alpha[ThreeWayUpper] * ns[0][Bottom][prv])
+	s[v-1][Bottom][cur] = (junctionPressure - s[v-1][Top][prv]) * vt.DampingFactor
+	s[v][Top][cur] = ((junctionPressure - s[v][Bottom][prv]) * vt.DampingFactor) + (wg.FricTaps[v] * frication)
+	ns[0][Top][cur] = (junctionPressure - ns[0][Bottom][prv]) * vt.DampingFactor
+
+	// reflected signal at mouth goes through a lowpass filter
+	s[n-1][Bottom][cur] = vt.DampingFactor *
+		vt.MouthReflectionFilter.Filter(wg.Coefs[n-1]*s[n-1][Top][prv])
+
+	// output from mouth goes through a highpass filter
+	output := vt.MouthRadiationFilter.Filter((1.0 + wg.Coefs[n-1]) * s[n-1][Top][prv])
+
+	// nasal tract, with the reflected signal at the nose through a lowpass filter, and the output through a highpass filter
+	nn := wg.NasalSects
+	for i := 0; i < nn-1; i++ {
+		delta := wg.NasalCoefs[i] * (ns[i][Top][prv] - ns[i+1][Bottom][prv])
+		ns[i+1][Top][cur] = (ns[i][Top][prv] + delta) * vt.DampingFactor
+		ns[i][Bottom][cur] = (ns[i+1][Bottom][prv] + delta) * vt.DampingFactor
+	}
+	ns[nn-1][Bottom][cur] = vt.DampingFactor *
+		vt.NasalReflectionFilter.Filter(wg.NasalCoefs[nn-1]*ns[nn-1][Top][prv])
+	nasal := vt.NasalRadiationFilter.Filter((1.0 + wg.NasalCoefs[nn-1]) * ns[nn-1][Top][prv])
+
+	return output + nasal
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+// waveguideTract returns an initialized VocalTract using the Waveguide of nSects sections (0 for
+// the 10 section model), with a voiced vowel as the control params
+func waveguideTract(t *testing.T, nSects int) *VocalTract {
+	t.Helper()
+	vt := &VocalTract{}
+	vt.Config.Defaults()
+	vt.Config.NSects = nSects
+	if err := vt.Init(); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	vt.Initialize()
+	vt.CurControl.Init()
+	vt.CurControl.GlotVol = 60
+	return vt
+}
+
+func TestSetSectRadii(t *testing.T) {
+	vt := waveguideTract(t, 0)
+	if err := vt.SetSectRadii(make([]float32, 10)); err == nil {
+		t.Errorf("SetSectRadii for the 10 section model: no error")
+	}
+
+	vt = waveguideTract(t, 20)
+	n := vt.Waveguide.NSects
+	if err := vt.SetSectRadii(make([]float32, n-1)); err == nil {
+		t.Errorf("SetSectRadii with %d radii for %d sections: no error", n-1, n)
+	}
+	radii := make([]float32, n)
+	for i := range radii {
+		radii[i] = 1
+	}
+	nan := float32(math.NaN())
+	radii[2], radii[5], radii[9] = 0, -0.5, nan
+	if err := vt.SetSectRadii(radii); err != nil {
+		t.Fatalf("SetSectRadii error: %v", err)
+	}
+	for _, i := range []int{2, 5, 9} {
+		if r := vt.Waveguide.SectRadii[i]; r != GsTrmTubeMinRadius {
+			t.Errorf("radius %v of section %d set to %v, want GsTrmTubeMinRadius", radii[i], i, r)
+		}
+	}
+	for i := 0; i < 8; i++ {
+		if err := vt.Synthesize(false); err != nil {
+			t.Fatalf("Synthesize error: %v", err)
+		}
+	}
+	for i, c := range vt.Waveguide.Coefs {
+		if c != c || c < -1 || c > 1 {
+			t.Errorf("coefficient %d = %v, want within -1 - 1", i, c)
+		}
+	}
+	for i, v := range vt.OutputData {
+		if v != v {
+			t.Fatalf("sample %d is NaN", i)
+		}
+	}
+}
@@ -32,23 +32,34 @@ import (
 	"math"
 )
 
-// compile with oversampling or plain oscillator
-// const OversamplingOscillator = 1
-
 // glottal source oscillator table variables
 const TableLength = 512
 const TableModulus = TableLength - 1
 
-//  oversampling fir filter characteristics
-//const FirBeta = .2
-//const FirGamma = .1
-//const FirCutoff = .00000001
+// oversampling fir filter characteristics
+const FirBeta = .2
+const FirGamma = .1
+const FirCutoff = .00000001
 
 type WaveForm int32
 
 const (
+	// Pulse is the gnuspeech polynomial glottal pulse, whose fall shortens with increasing amplitude
 	Pulse = iota
+
+	// Sine is a pure sine tone
 	Sine
+
+	// LF is the Liljencrants-Fant glottal flow, shaped by Rd
+	LF
+
+	// Rosenberg is the Rosenberg trigonometric glottal flow, shaped by OpenQuot
+	Rosenberg
+
+	// KLGLOTT88 is the Klatt & Klatt polynomial glottal flow, shaped by OpenQuot
+	KLGLOTT88
+
+	WaveFormN
 )
 
 //go:generate stringer -type=WaveForm
@@ -61,19 +72,55 @@ type WavetableGlottalSource struct {
 	BasicIncrement  float32
 	CurrentPosition float32
 	Wavetable       [TableLength]float32
+	Oversample      bool    // use the 2x oversampling oscillator, which decimates through the FirFilter, reducing aliasing at high pitches
+	Rd              float32 // shape of the LF glottal flow, from 0.3 (tense) through 1 (modal) to 2.7 (breathy)
+	OpenQuot        float32 // open quotient (fraction of the period the glottis is open) of the Rosenberg and KLGLOTT88 glottal flows
+	Tilt            float32 // spectral tilt: attenuation of the source in dB at 3 kHz, by a one-pole lowpass filter
+	Jitter          float32 // maximum random perturbation of the period of each glottal cycle, as a fraction of the period
+	Shimmer         float32 // maximum random perturbation of the amplitude of each glottal cycle, as a fraction of the amplitude
 	FirFilter       FirFilter
+	TiltCoef        float32     // coefficient of the tilt filter
+	TiltPrev        float32     // previous output of the tilt filter
+	FreqScale       float32     // jitter scaling of the frequency of the current cycle
+	AmpScale        float32     // shimmer scaling of the amplitude of the current cycle
+	Perturb         NoiseSource // source of the jitter and shimmer perturbations
 }
 
-// Init calculates the initial glottal pulse and stores it in the wavetable, for use in the oscillator.
-func (wgs *WavetableGlottalSource) Init(wType WaveForm, sampleRate, tp, tnMin, tnMax float32) {
+// Init calculates the initial glottal pulse and stores it in the wavetable, for use in the oscillator,
+// and initializes the FirFilter if Oversample is set, returning its error -- tp, tnMin and tnMax are
+// the rise and min and max fall times of the Pulse as percentages of the period, and the rise fraction
+// of the open phase of the Rosenberg flow is tp / (tp + tnMax)
+func (wgs *WavetableGlottalSource) Init(wType WaveForm, sampleRate, tp, tnMin, tnMax float32) error {
+	wgs.BasicIncrement = float32(TableLength) / sampleRate
+	wgs.InitTable(wType, tp, tnMin, tnMax)
+	wgs.TiltCoef = TiltCoef(wgs.Tilt, sampleRate)
+
+	if wgs.Oversample {
+		return wgs.FirFilter.Init(FirBeta, FirGamma, FirCutoff)
+	}
+	return nil
+}
+
+// SetVoice updates the oscillator for a new sample rate and pulse times while it is running, without
+// resetting it, e.g., as voices are morphed -- only the Pulse and Rosenberg tables depend on the pulse times
+func (wgs *WavetableGlottalSource) SetVoice(wType WaveForm, sampleRate, tp, tnMin, tnMax float32) {
+	wgs.BasicIncrement = float32(TableLength) / sampleRate
+	wgs.TiltCoef = TiltCoef(wgs.Tilt, sampleRate)
+	if wType == Pulse || wType == Rosenberg {
+		wgs.InitTable(wType, tp, tnMin, tnMax)
+	}
+}
+
+// InitTable fills the wavetable with one period of the given waveform
+func (wgs *WavetableGlottalSource) InitTable(wType WaveForm, tp, tnMin, tnMax float32) {
 	wgs.TableDiv1 = int(math.Round(float64(TableLength * (tp / 100.0))))
 	wgs.TableDiv2 = int(math.Round(float64(TableLength * ((tp + tnMax) / 100.0))))
 	wgs.TnLength = float32(wgs.TableDiv2 - wgs.TableDiv1)
 	wgs.TnDelta = float32(math.Round(float64(TableLength * (tnMax - tnMin) / 100.0)))
-	wgs.BasicIncrement = float32(TableLength) / sampleRate
 
-	// initialize the wavetable with either a glottal pulse or sine tone
-	if wType == Pulse {
+	// initialize the wavetable with a glottal pulse, glottal flow model or sine tone
+	switch wType {
+	case Pulse:
 		// calculate rise portion of wave table
 		for i := 0; i < wgs.TableDiv1; i++ {
 			x := float32(i) / float32(wgs.TableDiv1)
@@ -94,7 +141,13 @@ func (wgs *WavetableGlottalSource) Init(wType WaveForm, sampleRate, tp, tnMin, t
 		for i := wgs.TableDiv2; i < TableLength; i++ {
 			wgs.Wavetable[i] = 0.0
 		}
-	} else {
+	case LF:
+		LFWave(wgs.Rd, wgs.Wavetable[:])
+	case Rosenberg:
+		RosenbergWave(wgs.OpenQuot, tp/(tp+tnMax), wgs.Wavetable[:])
+	case KLGLOTT88:
+		KLGLOTT88Wave(wgs.OpenQuot, wgs.Wavetable[:])
+	default:
 		// sine wave
 		for i := 0; i < TableLength; i++ {
 			wgs.Wavetable[i] = math32.Sin((float32(i) / float32(TableLength) * 2.0 * math.Pi))
@@ -102,15 +155,26 @@ func (wgs *WavetableGlottalSource) Init(wType WaveForm, sampleRate, tp, tnMin, t
 	}
 }
 
-// ToDo:
-//#if OVERSAMPLING_OSCILLATOR
-// firFilter_.reset(new FIRFilter(FIR_BETA, FIR_GAMMA, FIR_CUTOFF));
-// #endif
-
-// Reset resets the current position and the Fir Filter
+// Reset resets the current position, the Fir Filter, the tilt filter and the perturbations
 func (wgs *WavetableGlottalSource) Reset() {
 	wgs.CurrentPosition = 0
 	wgs.FirFilter.Reset()
+	wgs.TiltPrev = 0
+	wgs.FreqScale = 1
+	wgs.AmpScale = 1
+	wgs.Perturb.Reset()
+}
+
+// NewCycle draws the jitter and shimmer perturbations of the next glottal cycle
+func (wgs *WavetableGlottalSource) NewCycle() {
+	wgs.FreqScale = 1
+	if wgs.Jitter > 0 {
+		wgs.FreqScale = 1 / (1 + 2*wgs.Jitter*wgs.Perturb.GetSample())
+	}
+	wgs.AmpScale = 1
+	if wgs.Shimmer > 0 {
+		wgs.AmpScale = 1 + 2*wgs.Shimmer*wgs.Perturb.GetSample()
+	}
 }
 
 // Update rewrites the changeable part of the glottal pulse according to the amplitude
@@ -134,52 +198,40 @@ func (wgs *WavetableGlottalSource) Update(amplitude float32) {
 
 }
 
-// IncrementTablePosition increments the position in the wavetable according to the desired frequency
+// IncrementTablePosition increments the position in the wavetable according to the desired frequency --
+// at the start of each cycle the jitter and shimmer perturbations are updated
 func (wgs *WavetableGlottalSource) IncrementTablePos(frequency float32) {
+	if wgs.FreqScale > 0 {
+		frequency *= wgs.FreqScale
+	}
+	prv := wgs.CurrentPosition
 	wgs.CurrentPosition = Mod0(wgs.CurrentPosition + (frequency * wgs.BasicIncrement))
+	if wgs.CurrentPosition < prv {
+		wgs.NewCycle()
+	}
 }
 
-// ToDo:
-/******************************************************************************
- *
- *  function:  oscillator
- *
- *  purpose:   Is a 2X oversampling interpolating wavetable
- *             oscillator.
- *
- ******************************************************************************/
-//#if OVERSAMPLING_OSCILLATOR
-//float
-//WavetableGlottalSource::getSample(float frequency)  /*  2X OVERSAMPLING OSCILLATOR  */
-//{
-//  int lowerPosition, upperPosition;
-//  float interpolatedValue, output;
-//
-//  for (int i = 0; i < 2; i++) {
-//    /*  FIRST INCREMENT THE TABLE POSITION, DEPENDING ON FREQUENCY  */
-//    incrementTablePosition(frequency / 2.0);
-//
-//    /*  FIND SURROUNDING INTEGER TABLE POSITIONS  */
-//    lowerPosition = static_cast<int>(currentPosition_);
-//    upperPosition = static_cast<int>(mod0(lowerPosition + 1));
-//
-//    /*  CALCULATE INTERPOLATED TABLE VALUE  */
-//    interpolatedValue = wavetable_[lowerPosition] +
-//      ((currentPosition_ - lowerPosition) *
-//       (wavetable_[upperPosition] - wavetable_[lowerPosition]));
-//
-//    /*  PUT VALUE THROUGH FIR FILTER  */
-//    output = firFilter_->filter(interpolatedValue, i);
-//  }
-//
-//  /*  SINCE WE DECIMATE, TAKE ONLY THE SECOND OUTPUT VALUE  */
-//  return output;
-//}
-//#else
-// #endif
-
-// Get sample from plain oscillator
+// GetSample returns the next sample of the oscillator at the given frequency,
+// from the 2x oversampling oscillator if Oversample is set
 func (wgs *WavetableGlottalSource) GetSample(frequency float32) float32 {
+	var value float32
+	if wgs.Oversample {
+		value = wgs.GetSampleOversample(frequency)
+	} else {
+		value = wgs.GetSamplePlain(frequency)
+	}
+	if wgs.AmpScale > 0 {
+		value *= wgs.AmpScale
+	}
+	if wgs.TiltCoef > 0 {
+		value = (1-wgs.TiltCoef)*value + wgs.TiltCoef*wgs.TiltPrev
+		wgs.TiltPrev = value
+	}
+	return value
+}
+
+// GetSamplePlain is the plain interpolating wavetable oscillator
+func (wgs *WavetableGlottalSource) GetSamplePlain(frequency float32) float32 {
 	// first increment the table position, depending on frequency
 	wgs.IncrementTablePos(frequency)
 
@@ -194,6 +246,30 @@ func (wgs *WavetableGlottalSource) GetSample(frequency float32) float32 {
 	return value
 }
 
+// GetSampleOversample is a 2x oversampling interpolating wavetable oscillator -- two interpolated
+// table values are computed per sample, at half the frequency increment, and put through the
+// FirFilter, which decimates by returning only the second output
+func (wgs *WavetableGlottalSource) GetSampleOversample(frequency float32) float32 {
+	var output float32
+	for i := 0; i < 2; i++ {
+		// first increment the table position, depending on frequency
+		wgs.IncrementTablePos(frequency / 2.0)
+
+		// find surrounding integer table positions
+		lowerPosition := int(wgs.CurrentPosition)
+		upperPosition := int(Mod0(float32(lowerPosition + 1)))
+
+		// calculate interpolated table value
+		value := wgs.Wavetable[lowerPosition] +
+			((wgs.CurrentPosition - float32(lowerPosition)) * (wgs.Wavetable[upperPosition] - wgs.Wavetable[lowerPosition]))
+
+		// put value through fir filter
+		output = wgs.FirFilter.Filter(value, i == 1)
+	}
+	// since we decimate, take only the second output value
+	return output
+}
+
 // Mod0 eturns the modulus of 'value', keeping it in the range 0 -> TableModulus
 func Mod0(value float32) float32 {
 	if value > TableModulus {
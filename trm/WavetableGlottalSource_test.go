@@ -0,0 +1,107 @@
+// Copyright (c) 2019, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFirFilter(t *testing.T) {
+	var ff FirFilter
+	if err := ff.Init(FirBeta, FirGamma, FirCutoff); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	if ff.NTaps%2 != 1 || len(ff.Coef) != ff.NTaps || len(ff.Data) != ff.NTaps {
+		t.Fatalf("NTaps = %d, with %d coefficients and %d data, want an odd number of each", ff.NTaps, len(ff.Coef), len(ff.Data))
+	}
+	sum := 0.0
+	for i, c := range ff.Coef {
+		sum += float64(c)
+		if c != ff.Coef[ff.NTaps-1-i] {
+			t.Errorf("coefficient %d = %v, not symmetric with %v", i, c, ff.Coef[ff.NTaps-1-i])
+		}
+	}
+	if math.Abs(sum-1) > 1e-3 {
+		t.Errorf("dc gain = %v, want 1", sum)
+	}
+	var y float32
+	for i := 0; i < 2*ff.NTaps; i++ {
+		ff.Filter(1, false)
+		y = ff.Filter(1, true)
+	}
+	if math.Abs(float64(y)-sum) > 1e-5 {
+		t.Errorf("step response = %v, want the dc gain %v", y, sum)
+	}
+
+	tests := []struct {
+		beta, gamma, cutoff float32
+	}{ // beta and gamma out of range, and gamma too small
+		{0, FirGamma, FirCutoff},
+		{0.5, FirGamma, FirCutoff},
+		{FirBeta, 0.5, FirCutoff},
+		{FirBeta, 0.01, FirCutoff},
+	}
+	for _, tt := range tests {
+		var ff FirFilter
+		if err := ff.Init(tt.beta, tt.gamma, tt.cutoff); err == nil {
+			t.Errorf("Init(%v, %v, %v): no error", tt.beta, tt.gamma, tt.cutoff)
+		}
+	}
+}
+
+// aliasing returns the fraction of the power of the glottal source at sample rate sr and
+// frequency f0 that is not at the harmonics of f0 -- f0 must be a whole number of Hz, and
+// the harmonics of f0 above the Nyquist frequency are aliased between the harmonics below it
+func aliasing(gs *WavetableGlottalSource, sr, f0 int) float64 {
+	for i := 0; i < sr; i++ { // 1 sec to settle
+		gs.GetSample(float32(f0))
+	}
+	x := make([]float64, sr) // 1 sec, a whole number of periods
+	mean := 0.0
+	for i := range x {
+		x[i] = float64(gs.GetSample(float32(f0)))
+		mean += x[i]
+	}
+	mean /= float64(len(x))
+	tot := 0.0
+	for i := range x {
+		x[i] -= mean
+		tot += x[i] * x[i]
+	}
+	harm := 0.0
+	for f := f0; f < sr/2; f += f0 {
+		re, im := 0.0, 0.0
+		for i, v := range x {
+			ph := 2 * math.Pi * float64(f) * float64(i) / float64(sr)
+			re += v * math.Cos(ph)
+			im += v * math.Sin(ph)
+		}
+		harm += 2 * (re*re + im*im) / float64(len(x))
+	}
+	return (tot - harm) / tot
+}
+
+func TestOversample(t *testing.T) {
+	const sr, f0 = 20000, 1300
+	var plain, over WavetableGlottalSource
+	over.Oversample = true
+	if err := plain.Init(Pulse, sr, 40, 16, 32); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	if err := over.Init(Pulse, sr, 40, 16, 32); err != nil {
+		t.Fatalf("Init with Oversample error: %v", err)
+	}
+	if over.FirFilter.NTaps == 0 {
+		t.Fatalf("Init with Oversample did not initialize the FirFilter")
+	}
+	plain.Reset()
+	over.Reset()
+	pa := aliasing(&plain, sr, f0)
+	oa := aliasing(&over, sr, f0)
+	if !(oa < pa/10) {
+		t.Errorf("aliased power with Oversample = %v, not less than a tenth of that without = %v", oa, pa)
+	}
+}